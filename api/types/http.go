@@ -12,3 +12,8 @@ type HTTPError struct {
 func (herr HTTPError) Error() string {
 	return herr.ErrorMessage
 }
+
+// RunnerReportChecksumHeader is the HTTP header carrying hex-encoded SHA256
+// checksum of runner's report. The server rejects the upload if the received
+// data doesn't match it.
+const RunnerReportChecksumHeader = "X-Kperf-Report-Sha256"
@@ -3,7 +3,10 @@
 
 package types
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 // ContentType represents the format of response.
 type ContentType string
@@ -33,6 +36,8 @@ type LoadProfile struct {
 	Description string `json:"description,omitempty" yaml:"description"`
 	// Spec defines behavior of load profile.
 	Spec LoadProfileSpec `json:"spec" yaml:"spec"`
+	// SLO defines the thresholds which the result should meet.
+	SLO *SLO `json:"slo,omitempty" yaml:"slo,omitempty"`
 }
 
 // LoadProfileSpec defines the load traffic for traget resource.
@@ -53,11 +58,36 @@ type LoadProfileSpec struct {
 	// retrying upon receiving "Retry-After" headers and 429 status-code
 	// in the response (<= 0 means no retry).
 	MaxRetries int `json:"maxRetries" yaml:"maxRetries"`
+	// ConnChurn defines how clients drop and rebuild their connections.
+	// The connections are long-lived if it's not set.
+	ConnChurn *ConnChurn `json:"connChurn,omitempty" yaml:"connChurn,omitempty"`
+	// TimeSeriesInterval groups statistics by the interval in report
+	// (zero is disabled).
+	TimeSeriesInterval time.Duration `json:"timeSeriesInterval,omitempty" yaml:"timeSeriesInterval,omitempty"`
 	// Requests defines the different kinds of requests with weights.
 	// The executor should randomly pick by weight.
 	Requests []*WeightedRequest
 }
 
+// ConnChurn defines how clients drop and rebuild their connections so that
+// kube-apiserver has to handle TLS handshakes and authentication again.
+type ConnChurn struct {
+	// EveryRequests rebuilds client's connection after it sends the given
+	// number of requests (zero is disabled).
+	EveryRequests int `json:"everyRequests,omitempty" yaml:"everyRequests,omitempty"`
+	// Interval rebuilds client's connection periodically (zero is disabled).
+	Interval time.Duration `json:"interval,omitempty" yaml:"interval,omitempty"`
+	// Jitter adds random duration in [0, Jitter) to each Interval so that
+	// the clients don't reconnect at the same time.
+	Jitter time.Duration `json:"jitter,omitempty" yaml:"jitter,omitempty"`
+	// StormAfter forces all the clients to reconnect at once after the
+	// benchmark has been running for the given duration (zero is disabled).
+	//
+	// NOTE: It's used to simulate that thousands of kubelets reconnect
+	// after kube-apiserver rollout.
+	StormAfter time.Duration `json:"stormAfter,omitempty" yaml:"stormAfter,omitempty"`
+}
+
 // KubeGroupVersionResource identifies the resource URI.
 type KubeGroupVersionResource struct {
 	// Group is the name about a collection of related functionality.
@@ -73,6 +103,9 @@ type KubeGroupVersionResource struct {
 type WeightedRequest struct {
 	// Shares defines weight in the same group.
 	Shares int `json:"shares" yaml:"shares"`
+	// Tag is optional name of this request. The report aggregates
+	// statistics by tag so that requests can be grouped by intent.
+	Tag string `json:"tag,omitempty" yaml:"tag,omitempty"`
 	// StaleList means this list request with zero resource version.
 	StaleList *RequestList `json:"staleList,omitempty" yaml:"staleList,omitempty"`
 	// QuorumList means this list request without kube-apiserver cache.
@@ -166,6 +199,12 @@ func (lp LoadProfile) Validate() error {
 	if lp.Version != 1 {
 		return fmt.Errorf("version should be 1")
 	}
+
+	if lp.SLO != nil {
+		if err := lp.SLO.Validate(); err != nil {
+			return fmt.Errorf("slo: %v", err)
+		}
+	}
 	return lp.Spec.Validate()
 }
 
@@ -192,6 +231,16 @@ func (spec LoadProfileSpec) Validate() error {
 		return err
 	}
 
+	if spec.TimeSeriesInterval < 0 {
+		return fmt.Errorf("timeSeriesInterval requires >= 0: %v", spec.TimeSeriesInterval)
+	}
+
+	if spec.ConnChurn != nil {
+		if err := spec.ConnChurn.Validate(); err != nil {
+			return fmt.Errorf("connChurn: %v", err)
+		}
+	}
+
 	for idx, req := range spec.Requests {
 		if err := req.Validate(); err != nil {
 			return fmt.Errorf("idx: %v request: %v", idx, err)
@@ -200,6 +249,30 @@ func (spec LoadProfileSpec) Validate() error {
 	return nil
 }
 
+// Validate verifies fields of ConnChurn.
+func (c ConnChurn) Validate() error {
+	if c.EveryRequests < 0 {
+		return fmt.Errorf("everyRequests requires >= 0: %v", c.EveryRequests)
+	}
+
+	if c.Interval < 0 {
+		return fmt.Errorf("interval requires >= 0: %v", c.Interval)
+	}
+
+	if c.Jitter < 0 {
+		return fmt.Errorf("jitter requires >= 0: %v", c.Jitter)
+	}
+
+	if c.Jitter > 0 && c.Interval == 0 {
+		return fmt.Errorf("jitter only works with interval")
+	}
+
+	if c.StormAfter < 0 {
+		return fmt.Errorf("stormAfter requires >= 0: %v", c.StormAfter)
+	}
+	return nil
+}
+
 // Validate verifies fields of WeightedRequest.
 func (r WeightedRequest) Validate() error {
 	if r.Shares < 0 {
@@ -5,6 +5,7 @@ package types
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -21,6 +22,12 @@ spec:
   conns: 2
   client: 1
   contentType: json
+  connChurn:
+    everyRequests: 100
+    interval: 30s
+    jitter: 5s
+    stormAfter: 10m
+  timeSeriesInterval: 5s
   requests:
   - staleGet:
       group: core
@@ -29,6 +36,7 @@ spec:
       namespace: default
       name: x1
     shares: 100
+    tag: get-pod
   - quorumGet:
       group: core
       version: v1
@@ -87,7 +95,15 @@ spec:
 	assert.Equal(t, 2, target.Spec.Conns)
 	assert.Len(t, target.Spec.Requests, 7)
 
+	assert.NotNil(t, target.Spec.ConnChurn)
+	assert.Equal(t, 100, target.Spec.ConnChurn.EveryRequests)
+	assert.Equal(t, 30*time.Second, target.Spec.ConnChurn.Interval)
+	assert.Equal(t, 5*time.Second, target.Spec.ConnChurn.Jitter)
+	assert.Equal(t, 10*time.Minute, target.Spec.ConnChurn.StormAfter)
+	assert.Equal(t, 5*time.Second, target.Spec.TimeSeriesInterval)
+
 	assert.Equal(t, 100, target.Spec.Requests[0].Shares)
+	assert.Equal(t, "get-pod", target.Spec.Requests[0].Tag)
 	assert.NotNil(t, target.Spec.Requests[0].StaleGet)
 	assert.Equal(t, "pods", target.Spec.Requests[0].StaleGet.Resource)
 	assert.Equal(t, "v1", target.Spec.Requests[0].StaleGet.Version)
@@ -18,6 +18,26 @@ const (
 	// ResponseErrorTypeConnection indicates that error is related to connection.
 	// For instance, connection refused caused by server down.
 	ResponseErrorTypeConnection ResponseErrorType = "connection"
+	// ResponseErrorTypeAPFRejected indicates that kube-apiserver's
+	// APIPriorityAndFairness rejected the request with 429 code.
+	ResponseErrorTypeAPFRejected ResponseErrorType = "apf-rejected"
+	// ResponseErrorTypeMaxInflightRejected indicates that kube-apiserver's
+	// max-inflight filter rejected the request with 429 code.
+	ResponseErrorTypeMaxInflightRejected ResponseErrorType = "max-inflight-rejected"
+	// ResponseErrorTypeClientTimeout indicates that the request didn't
+	// complete before client-side timeout.
+	ResponseErrorTypeClientTimeout ResponseErrorType = "client-timeout"
+	// ResponseErrorTypeContextCanceled indicates that the request was
+	// canceled by client.
+	ResponseErrorTypeContextCanceled ResponseErrorType = "context-canceled"
+	// ResponseErrorTypeTLS indicates that error comes from TLS layer, for
+	// instance, untrusted certificate.
+	ResponseErrorTypeTLS ResponseErrorType = "tls"
+	// ResponseErrorTypeDNS indicates that client failed to resolve host.
+	ResponseErrorTypeDNS ResponseErrorType = "dns"
+	// ResponseErrorTypeHTTP2RefusedStream indicates that server refused the
+	// stream with REFUSED_STREAM code before processing it.
+	ResponseErrorTypeHTTP2RefusedStream ResponseErrorType = "http2-refused-stream"
 )
 
 // ResponseError is the record about that error.
@@ -30,22 +50,193 @@ type ResponseError struct {
 	Duration float64 `json:"duration"`
 	// Type indicates that category to which the error belongs.
 	Type ResponseErrorType `json:"type"`
-	// Code only works when Type is http.
+	// Code is http code. It only works when error comes from http response,
+	// for instance, Type is http or apf-rejected.
 	Code int `json:"code"`
-	// Message shows error message for this error.
+	// Reason is the apiserver Status's reason, for instance, TooManyRequests.
 	//
-	// NOTE: When Type is http, this field will be empty.
+	// NOTE: It only works when Code isn't zero.
+	Reason string `json:"reason,omitempty"`
+	// Message shows error message for this error. When Code isn't zero, it's
+	// the apiserver Status's message.
 	Message string `json:"message"`
+	// Tag is the request's tag defined by load profile.
+	Tag string `json:"tag,omitempty"`
+}
+
+// ErrorCount is the exact number of errors with the same type, code and tag.
+type ErrorCount struct {
+	// Type indicates that category to which the errors belong.
+	Type ResponseErrorType `json:"type"`
+	// Code is http code if any.
+	Code int `json:"code,omitempty"`
+	// Tag is the request's tag defined by load profile.
+	Tag string `json:"tag,omitempty"`
+	// Count is the number of errors.
+	Count int64 `json:"count"`
+}
+
+// RunnerSelfStats is the runner's own resource usage during benchmark. It
+// helps to tell whether the runner, instead of kube-apiserver, is the
+// bottleneck.
+type RunnerSelfStats struct {
+	// CPUSeconds is the CPU time consumed by runner process.
+	CPUSeconds float64 `json:"cpuSeconds"`
+	// AvgCPUCores is the average CPU usage in cores.
+	AvgCPUCores float64 `json:"avgCPUCores"`
+	// MaxCPUCores is the maximum CPU usage in cores within one sampling
+	// interval.
+	MaxCPUCores float64 `json:"maxCPUCores"`
+	// AvailableCPUCores is the CPU quota of runner's cgroup in cores. It's
+	// GOMAXPROCS if there is no quota.
+	AvailableCPUCores float64 `json:"availableCPUCores"`
+	// ThrottledSeconds is the time throttled by cgroup's CPU quota.
+	ThrottledSeconds float64 `json:"throttledSeconds"`
+	// MaxRSSBytes is the peak resident set size.
+	MaxRSSBytes int64 `json:"maxRSSBytes"`
+	// MaxGoroutines is the maximum number of goroutines.
+	MaxGoroutines int `json:"maxGoroutines"`
+	// NumGC is the number of completed GC cycles.
+	NumGC int64 `json:"numGC"`
+	// GCPauseSeconds is the total GC pause time.
+	GCPauseSeconds float64 `json:"gcPauseSeconds"`
+	// MaxGCPauseSeconds is the longest recent GC pause.
+	MaxGCPauseSeconds float64 `json:"maxGCPauseSeconds"`
+	// PercentileLimiterLags represents the distribution of delay in seconds
+	// between intended dispatch time based on rate and actual dispatch time.
+	PercentileLimiterLags [][2]float64 `json:"percentileLimiterLags,omitempty"`
+}
+
+// APIServerMetricsDelta is the change of one kube-apiserver replica's
+// metrics during benchmark. The key of series is the metric name with
+// sorted labels, for instance,
+// apiserver_flowcontrol_rejected_requests_total{priority_level="workload-low",reason="queue-full"}.
+type APIServerMetricsDelta struct {
+	// Duration is the time between two scrapes.
+	Duration string `json:"duration"`
+	// Counters stores the increase of counters.
+	Counters map[string]float64 `json:"counters,omitempty"`
+	// Gauges stores the values of gauges as [before, after].
+	Gauges map[string][2]float64 `json:"gauges,omitempty"`
+	// Histograms stores the increase of histograms.
+	Histograms map[string]*HistogramDelta `json:"histograms,omitempty"`
+}
+
+// HistogramDelta is the increase of one Prometheus histogram series.
+type HistogramDelta struct {
+	// Count is the number of observations.
+	Count uint64 `json:"count"`
+	// Sum is the sum of observations.
+	Sum float64 `json:"sum"`
+	// Percentiles represents the distribution estimated from buckets, like
+	// Prometheus's histogram_quantile.
+	Percentiles [][2]float64 `json:"percentiles,omitempty"`
+}
+
+// LatencyHistogram is the serialized form of mergeable latency histogram.
+//
+// Each bucket covers (gamma^(index-1), gamma^index] seconds, where gamma is
+// (1 + RelativeAccuracy) / (1 - RelativeAccuracy). The histograms with the same
+// RelativeAccuracy can be merged by adding counts of the same index.
+type LatencyHistogram struct {
+	// RelativeAccuracy is the maximum relative error of percentile.
+	RelativeAccuracy float64 `json:"relativeAccuracy"`
+	// Count is the number of observed latencies.
+	Count int64 `json:"count"`
+	// Sum is the sum of observed latencies in seconds.
+	Sum float64 `json:"sum"`
+	// Min is the minimum observed latency in seconds.
+	Min float64 `json:"min"`
+	// Max is the maximum observed latency in seconds.
+	Max float64 `json:"max"`
+	// ZeroCount is the number of latencies which are too small to be
+	// indexed, including zero.
+	ZeroCount int64 `json:"zeroCount,omitempty"`
+	// Buckets stores non-empty buckets as [index, count] sorted by index.
+	Buckets [][2]int64 `json:"buckets,omitempty"`
+}
+
+// PriorityLevelStats is the statistics of requests which were handled by
+// one of kube-apiserver's PriorityLevelConfigurations.
+type PriorityLevelStats struct {
+	// Total is the number of requests.
+	Total int `json:"total"`
+	// FlowSchemas is the number of requests group by matched FlowSchema.
+	FlowSchemas map[string]int `json:"flowSchemas,omitempty"`
+	// TooManyRequests is the number of requests rejected with 429 code.
+	TooManyRequests int `json:"tooManyRequests"`
+	// Retries is the total number of retries.
+	Retries int `json:"retries"`
+	// Histogram stores latency histogram for succeeded requests.
+	Histogram *LatencyHistogram `json:"histogram,omitempty"`
+	// PercentileLatencies represents the latency distribution in seconds.
+	PercentileLatencies [][2]float64 `json:"percentileLatencies,omitempty"`
+}
+
+// RequestStats is the statistics of a group of requests, for instance, the
+// requests with the same tag.
+type RequestStats struct {
+	// Verb is the request's verb, for instance, LIST.
+	Verb string `json:"verb,omitempty"`
+	// Group is the target resource's API group. Empty value means core.
+	Group string `json:"group,omitempty"`
+	// Resource is the target resource, for instance, pods.
+	Resource string `json:"resource,omitempty"`
+	// Scope is cluster, namespace or resource.
+	Scope string `json:"scope,omitempty"`
+	// Total is the number of requests, including failed requests.
+	Total int `json:"total"`
+	// ErrorStats means summary of errors group by type.
+	ErrorStats map[string]int32 `json:"errorStats,omitempty"`
+	// Histogram stores latency histogram for succeeded requests.
+	Histogram *LatencyHistogram `json:"histogram,omitempty"`
+	// PercentileLatencies represents the latency distribution in seconds.
+	PercentileLatencies [][2]float64 `json:"percentileLatencies,omitempty"`
+}
+
+// TimeSeriesBucket is the statistics of requests which completed within
+// one interval.
+type TimeSeriesBucket struct {
+	// Timestamp is the start of interval.
+	Timestamp time.Time `json:"timestamp"`
+	// Total is the number of requests, including failed requests.
+	Total int `json:"total"`
+	// ReceivedBytes is the bytes read from apiserver.
+	ReceivedBytes int64 `json:"receivedBytes"`
+	// ErrorStats means summary of errors group by type.
+	ErrorStats map[string]int32 `json:"errorStats,omitempty"`
+	// Histogram stores latency histogram for succeeded requests.
+	Histogram *LatencyHistogram `json:"histogram,omitempty"`
+	// PercentileLatencies represents the latency distribution in seconds.
+	PercentileLatencies [][2]float64 `json:"percentileLatencies,omitempty"`
 }
 
 // ResponseStats is the report about benchmark result.
 type ResponseStats struct {
-	// Errors stores all the observed errors.
+	// Errors stores sampled examples of the observed errors.
 	Errors []ResponseError
-	// LatenciesByURL stores all the observed latencies for each request.
-	LatenciesByURL map[string][]float64
+	// ErrorStats means summary of all the errors group by type.
+	ErrorStats map[string]int32
+	// ErrorCounts stores exact counts of errors group by type, code and tag,
+	// sorted by count in descending order.
+	ErrorCounts []ErrorCount
+	// HistogramsByURL stores latency histogram for each request URL if
+	// enabled.
+	HistogramsByURL map[string]*LatencyHistogram
+	// StatsByTag stores statistics group by request's tag.
+	StatsByTag map[string]*RequestStats
+	// StatsByResource stores statistics group by verb, group, resource
+	// and scope.
+	StatsByResource map[string]*RequestStats
 	// TotalReceivedBytes is total bytes read from apiserver.
 	TotalReceivedBytes int64
+	// PriorityLevels stores statistics group by PriorityLevelConfiguration.
+	//
+	// NOTE: The requests without flowcontrol response headers aren't
+	// included, for instance, connection errors.
+	PriorityLevels map[string]*PriorityLevelStats
+	// TimeSeries stores statistics group by interval, sorted by timestamp.
+	TimeSeries []TimeSeriesBucket
 }
 
 type RunnerMetricReport struct {
@@ -53,20 +244,130 @@ type RunnerMetricReport struct {
 	Total int `json:"total"`
 	// Duration means the time of benchmark.
 	Duration string `json:"duration"`
-	// Errors stores all the observed errors.
+	// Errors stores sampled examples of the observed errors.
 	Errors []ResponseError `json:"errors,omitempty"`
 	// ErrorStats means summary of errors group by type.
 	ErrorStats map[string]int32 `json:"errorStats,omitempty"`
+	// ErrorCounts stores exact counts of errors group by type, code and
+	// tag, sorted by count in descending order.
+	ErrorCounts []ErrorCount `json:"errorCounts,omitempty"`
 	// TotalReceivedBytes is total bytes read from apiserver.
 	TotalReceivedBytes int64 `json:"totalReceivedBytes"`
-	// LatenciesByURL stores all the observed latencies.
-	LatenciesByURL map[string][]float64 `json:"latenciesByURL,omitempty"`
+	// HistogramsByURL stores latency histogram for each request so that
+	// reports from different runners can be merged.
+	HistogramsByURL map[string]*LatencyHistogram `json:"histogramsByURL,omitempty"`
 	// PercentileLatencies represents the latency distribution in seconds.
 	PercentileLatencies [][2]float64 `json:"percentileLatencies,omitempty"`
 	// PercentileLatenciesByURL represents the latency distribution in seconds per request.
 	PercentileLatenciesByURL map[string][][2]float64 `json:"percentileLatenciesByURL,omitempty"`
+	// StatsByTag represents statistics group by request's tag.
+	StatsByTag map[string]*RequestStats `json:"statsByTag,omitempty"`
+	// StatsByResource represents statistics group by verb, group, resource
+	// and scope, for instance, "LIST pods cluster".
+	StatsByResource map[string]*RequestStats `json:"statsByResource,omitempty"`
+	// PriorityLevels represents statistics group by kube-apiserver's
+	// PriorityLevelConfiguration name.
+	PriorityLevels map[string]*PriorityLevelStats `json:"priorityLevels,omitempty"`
+	// TimeSeriesInterval is the interval of TimeSeries.
+	TimeSeriesInterval string `json:"timeSeriesInterval,omitempty"`
+	// TimeSeries represents statistics group by interval, sorted by
+	// timestamp.
+	TimeSeries []TimeSeriesBucket `json:"timeSeries,omitempty"`
+	// APIServerMetrics represents the change of kube-apiserver's metrics
+	// during benchmark, keyed by kube-apiserver replica's address.
+	APIServerMetrics map[string]*APIServerMetricsDelta `json:"apiserverMetrics,omitempty"`
+	// SelfStats represents runner's own resource usage during benchmark.
+	// In runner groups' summary, it's merged by MergeRunnerSelfStats in
+	// metrics package.
+	SelfStats *RunnerSelfStats `json:"selfStats,omitempty"`
+	// Warnings shows the issues which might make result unreliable, for
+	// instance, runner is CPU-throttled.
+	Warnings []string `json:"warnings,omitempty"`
+	// Interrupted means benchmark was cancelled, for instance, by SIGTERM,
+	// and the report only covers the requests finished before that.
+	Interrupted bool `json:"interrupted,omitempty"`
+	// Error is the reason why runner failed before or during benchmark. The
+	// report doesn't have statistics in that case.
+	Error string `json:"error,omitempty"`
+	// Verdict is the result of evaluating SLO if any.
+	Verdict *SLOVerdict `json:"verdict,omitempty"`
 }
 
-// TODO(weifu): build brand new struct for RunnerGroupsReport to include more
-// information, like how many runner groups, service account and flow control.
-type RunnerGroupsReport = RunnerMetricReport
+// RunnerGroupsReport is the summary of runner groups.
+type RunnerGroupsReport struct {
+	// RunnerMetricReport is the aggregated report from all the runners.
+	RunnerMetricReport
+	// Groups represents the report for each runner group.
+	Groups []RunnerGroupReport `json:"groups,omitempty"`
+	// FlowControl is the flowcontrol setting applied to runners.
+	FlowControl *RunnerGroupFlowControl `json:"flowControl,omitempty"`
+	// Partial means runner groups are still running and the summary only
+	// merges the reports uploaded so far.
+	Partial bool `json:"partial,omitempty"`
+	// IncludedRunners lists the runners merged into partial summary, sorted
+	// by name.
+	IncludedRunners []string `json:"includedRunners,omitempty"`
+}
+
+// RunnerGroupReport is the report of one runner group.
+type RunnerGroupReport struct {
+	// Name is the runner group's name.
+	Name string `json:"name"`
+	// Spec is the runner group's spec.
+	Spec *RunnerGroupSpec `json:"spec,omitempty"`
+	// Status is the runner group's status when summary was built.
+	Status *RunnerGroupStatus `json:"status,omitempty"`
+	// Report is the aggregated report from the runner group's runners.
+	Report *RunnerMetricReport `json:"report,omitempty"`
+	// Runners represents each runner's state, sorted by name.
+	Runners []RunnerReportStatus `json:"runners,omitempty"`
+}
+
+// RunnerReportStatus represents whether runner succeeded and uploaded
+// report.
+type RunnerReportStatus struct {
+	// Name is the runner's pod name.
+	Name string `json:"name"`
+	// State is one of pending, running, succeeded, failed or
+	// missing-report.
+	State RunnerReportState `json:"state"`
+	// Interrupted means runner was interrupted and uploaded partial report.
+	// It's only set in summary because listing runners doesn't read reports.
+	Interrupted bool `json:"interrupted,omitempty"`
+	// HasReport means runner's raw report can be downloaded from server.
+	HasReport bool `json:"hasReport,omitempty"`
+	// Interim means runner hasn't uploaded report and partial summary
+	// merges its latest interim snapshot instead. It's only set in partial
+	// summary.
+	Interim bool `json:"interim,omitempty"`
+	// SelfStats is runner's own resource usage from its report. It's only
+	// set in summary.
+	SelfStats *RunnerSelfStats `json:"selfStats,omitempty"`
+}
+
+// RunnerReportState is runner's state in runner group's report.
+type RunnerReportState string
+
+const (
+	// RunnerReportStatePending represents runner's pod hasn't started yet.
+	RunnerReportStatePending RunnerReportState = "pending"
+	// RunnerReportStateRunning represents runner is running and hasn't
+	// uploaded report yet.
+	RunnerReportStateRunning RunnerReportState = "running"
+	// RunnerReportStateSucceeded represents runner finished and uploaded
+	// report.
+	RunnerReportStateSucceeded RunnerReportState = "succeeded"
+	// RunnerReportStateFailed represents runner's pod failed.
+	RunnerReportStateFailed RunnerReportState = "failed"
+	// RunnerReportStateMissingReport represents runner's pod finished but
+	// there is no report.
+	RunnerReportStateMissingReport RunnerReportState = "missing-report"
+)
+
+// RunnerGroupFlowControl is the flowcontrol setting applied to runners.
+type RunnerGroupFlowControl struct {
+	// PriorityLevel is the name of PriorityLevelConfiguration.
+	PriorityLevel string `json:"priorityLevel"`
+	// MatchingPrecedence is FlowSchema's matchingPrecedence.
+	MatchingPrecedence int `json:"matchingPrecedence"`
+}
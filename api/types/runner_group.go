@@ -3,7 +3,13 @@
 
 package types
 
-import metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
 
 // RunnerGroup defines a set of runners with same load profile.
 type RunnerGroup struct {
@@ -30,6 +36,287 @@ type RunnerGroupSpec struct {
 	//
 	// FORMAT: APIVersion:Kind:Name:UID
 	OwnerReference *string `json:"ownerReference,omitempty" yaml:"ownerReference,omitempty"`
+	// Name identifies the runner group in other runner groups' StartAfter.
+	// It's optional.
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// StartAfter defines which runner group should be running or finished
+	// before deploying this runner group.
+	StartAfter *RunnerGroupStartAfter `json:"startAfter,omitempty" yaml:"startAfter,omitempty"`
+	// StartDelay is the time to wait before deploying this runner group. If
+	// StartAfter is set, it counts from when StartAfter's condition is met.
+	StartDelay time.Duration `json:"startDelay,omitempty" yaml:"startDelay,omitempty"`
+	// PodOverrides customizes runner's pod.
+	PodOverrides *RunnerPodOverrides `json:"podOverrides,omitempty" yaml:"podOverrides,omitempty"`
+	// Spread defines how to spread runners across nodes or zones so that
+	// runners don't share one node's NIC and CPU.
+	Spread *RunnerSpread `json:"spread,omitempty" yaml:"spread,omitempty"`
+	// Metrics exposes runners' Prometheus metrics during benchmark. It's
+	// disabled by default.
+	Metrics *RunnerMetrics `json:"metrics,omitempty" yaml:"metrics,omitempty"`
+}
+
+// RunnerMetrics defines how runners expose Prometheus metrics.
+type RunnerMetrics struct {
+	// Port is the container port to expose metrics on /metrics.
+	// Default: 8080.
+	Port int32 `json:"port,omitempty" yaml:"port,omitempty"`
+	// ScrapeAnnotations adds prometheus.io/scrape, prometheus.io/port and
+	// prometheus.io/path annotations to runner pods.
+	ScrapeAnnotations bool `json:"scrapeAnnotations,omitempty" yaml:"scrapeAnnotations,omitempty"`
+}
+
+// DefaultRunnerMetricsPort is the default port which runner exposes
+// Prometheus metrics on.
+const DefaultRunnerMetricsPort = int32(8080)
+
+// Validate verifies fields of RunnerMetrics.
+func (m RunnerMetrics) Validate() error {
+	if m.Port < 0 || m.Port > 65535 {
+		return fmt.Errorf("port requires [0, 65535]: %v", m.Port)
+	}
+	return nil
+}
+
+// RunnerSpread defines how to spread runners in the same runner group.
+type RunnerSpread struct {
+	// OnePerNode schedules at most one runner on each node.
+	OnePerNode bool `json:"onePerNode,omitempty" yaml:"onePerNode,omitempty"`
+	// TopologyConstraints spreads runners across topology domains, for
+	// instance, zones.
+	TopologyConstraints []RunnerTopologyConstraint `json:"topologyConstraints,omitempty" yaml:"topologyConstraints,omitempty"`
+}
+
+// RunnerTopologyConstraint is the same to Kubernetes pod's topology spread
+// constraint, matching runners in the same runner group.
+type RunnerTopologyConstraint struct {
+	// TopologyKey is node label's key, for instance,
+	// topology.kubernetes.io/zone.
+	TopologyKey string `json:"topologyKey" yaml:"topologyKey"`
+	// MaxSkew is the maximum difference of runners between any two
+	// topology domains. Default: 1.
+	MaxSkew int32 `json:"maxSkew,omitempty" yaml:"maxSkew,omitempty"`
+	// WhenUnsatisfiable is DoNotSchedule or ScheduleAnyway. Default:
+	// DoNotSchedule.
+	WhenUnsatisfiable string `json:"whenUnsatisfiable,omitempty" yaml:"whenUnsatisfiable,omitempty"`
+}
+
+// Validate verifies fields of RunnerSpread.
+func (s RunnerSpread) Validate() error {
+	for idx, c := range s.TopologyConstraints {
+		if c.TopologyKey == "" {
+			return fmt.Errorf("topologyConstraints[%d]: topologyKey is required", idx)
+		}
+		if c.MaxSkew < 0 {
+			return fmt.Errorf("topologyConstraints[%d]: maxSkew requires >= 0: %v", idx, c.MaxSkew)
+		}
+
+		switch c.WhenUnsatisfiable {
+		case "", "DoNotSchedule", "ScheduleAnyway":
+		default:
+			return fmt.Errorf("topologyConstraints[%d]: whenUnsatisfiable should be DoNotSchedule or ScheduleAnyway: %s",
+				idx, c.WhenUnsatisfiable)
+		}
+	}
+	return nil
+}
+
+// RunnerPodOverrides customizes runner's pod.
+type RunnerPodOverrides struct {
+	// Resources is runner container's resource requests and limits.
+	Resources *RunnerResources `json:"resources,omitempty" yaml:"resources,omitempty"`
+	// Tolerations allows runners to be scheduled to tainted nodes.
+	Tolerations []RunnerToleration `json:"tolerations,omitempty" yaml:"tolerations,omitempty"`
+	// PriorityClassName is runner pod's priority class.
+	PriorityClassName string `json:"priorityClassName,omitempty" yaml:"priorityClassName,omitempty"`
+	// ImagePullSecrets is the list of secret names to pull runner's image.
+	ImagePullSecrets []string `json:"imagePullSecrets,omitempty" yaml:"imagePullSecrets,omitempty"`
+	// DataVolume is the volume type to store runner's result, hostPath or
+	// emptyDir. Default: hostPath, which is /tmp on the node.
+	DataVolume string `json:"dataVolume,omitempty" yaml:"dataVolume,omitempty"`
+}
+
+// RunnerResources is runner container's resources, for instance,
+// {"cpu": "2", "memory": "4Gi"}.
+type RunnerResources struct {
+	// Requests is the minimum amount of resources required.
+	Requests map[string]string `json:"requests,omitempty" yaml:"requests,omitempty"`
+	// Limits is the maximum amount of resources allowed.
+	Limits map[string]string `json:"limits,omitempty" yaml:"limits,omitempty"`
+}
+
+// RunnerToleration is the same to Kubernetes pod's toleration.
+type RunnerToleration struct {
+	// Key is the taint key. Empty means matching all taint keys.
+	Key string `json:"key,omitempty" yaml:"key,omitempty"`
+	// Operator is Exists or Equal. Default: Equal.
+	Operator string `json:"operator,omitempty" yaml:"operator,omitempty"`
+	// Value is the taint value.
+	Value string `json:"value,omitempty" yaml:"value,omitempty"`
+	// Effect is NoSchedule, PreferNoSchedule or NoExecute. Empty means
+	// matching all taint effects.
+	Effect string `json:"effect,omitempty" yaml:"effect,omitempty"`
+	// TolerationSeconds is the period of time the toleration tolerates
+	// NoExecute taint.
+	TolerationSeconds *int64 `json:"tolerationSeconds,omitempty" yaml:"tolerationSeconds,omitempty"`
+}
+
+const (
+	// RunnerDataVolumeHostPath stores runner's result in node's /tmp.
+	RunnerDataVolumeHostPath = "hostPath"
+	// RunnerDataVolumeEmptyDir stores runner's result in pod's emptyDir.
+	RunnerDataVolumeEmptyDir = "emptyDir"
+)
+
+// Validate verifies fields of RunnerPodOverrides.
+func (o RunnerPodOverrides) Validate() error {
+	if o.Resources != nil {
+		for name, list := range map[string]map[string]string{
+			"requests": o.Resources.Requests,
+			"limits":   o.Resources.Limits,
+		} {
+			for k, v := range list {
+				if _, err := resource.ParseQuantity(v); err != nil {
+					return fmt.Errorf("resources.%s.%s: invalid quantity %s: %v", name, k, v, err)
+				}
+			}
+		}
+	}
+
+	for idx, t := range o.Tolerations {
+		switch t.Operator {
+		case "", "Equal":
+		case "Exists":
+			if t.Value != "" {
+				return fmt.Errorf("tolerations[%d]: value should be empty if operator is Exists", idx)
+			}
+		default:
+			return fmt.Errorf("tolerations[%d]: operator should be Exists or Equal: %s", idx, t.Operator)
+		}
+
+		switch t.Effect {
+		case "", "NoSchedule", "PreferNoSchedule", "NoExecute":
+		default:
+			return fmt.Errorf("tolerations[%d]: unknown effect %s", idx, t.Effect)
+		}
+	}
+
+	switch o.DataVolume {
+	case "", RunnerDataVolumeHostPath, RunnerDataVolumeEmptyDir:
+	default:
+		return fmt.Errorf("dataVolume should be %s or %s: %s",
+			RunnerDataVolumeHostPath, RunnerDataVolumeEmptyDir, o.DataVolume)
+	}
+	return nil
+}
+
+// RunnerGroupStartAfter is the condition of another runner group to start
+// this runner group.
+type RunnerGroupStartAfter struct {
+	// Name is the other runner group's name.
+	Name string `json:"name" yaml:"name"`
+	// State is running or finished. Default: finished.
+	//
+	// The running means the runner group's runners have been deployed.
+	State string `json:"state,omitempty" yaml:"state,omitempty"`
+}
+
+// Validate verifies fields of RunnerGroupSpec.
+func (spec RunnerGroupSpec) Validate() error {
+	if spec.StartDelay < 0 {
+		return fmt.Errorf("startDelay requires >= 0: %v", spec.StartDelay)
+	}
+
+	if spec.PodOverrides != nil {
+		if err := spec.PodOverrides.Validate(); err != nil {
+			return fmt.Errorf("podOverrides: %w", err)
+		}
+	}
+
+	if spec.Spread != nil {
+		if err := spec.Spread.Validate(); err != nil {
+			return fmt.Errorf("spread: %w", err)
+		}
+	}
+
+	if spec.Metrics != nil {
+		if err := spec.Metrics.Validate(); err != nil {
+			return fmt.Errorf("metrics: %w", err)
+		}
+	}
+
+	if after := spec.StartAfter; after != nil {
+		if after.Name == "" {
+			return fmt.Errorf("startAfter.name is required")
+		}
+		if after.Name == spec.Name {
+			return fmt.Errorf("startAfter.name can't be itself: %s", after.Name)
+		}
+
+		switch after.State {
+		case "", RunnerGroupStatusStateRunning, RunnerGroupStatusStateFinished:
+		default:
+			return fmt.Errorf("startAfter.state should be %s or %s: %s",
+				RunnerGroupStatusStateRunning, RunnerGroupStatusStateFinished, after.State)
+		}
+	}
+	return nil
+}
+
+// ValidateRunnerGroupSpecs verifies runner group specs which run together.
+// The names should be unique and StartAfter should refer to existing runner
+// group without cycle.
+func ValidateRunnerGroupSpecs(specs []*RunnerGroupSpec) error {
+	indexes := make(map[string]int, len(specs))
+	for idx, spec := range specs {
+		if err := spec.Validate(); err != nil {
+			return fmt.Errorf("runner group #%d: %w", idx, err)
+		}
+
+		if spec.Name == "" {
+			continue
+		}
+		if _, ok := indexes[spec.Name]; ok {
+			return fmt.Errorf("duplicate runner group name: %s", spec.Name)
+		}
+		indexes[spec.Name] = idx
+	}
+
+	for idx, spec := range specs {
+		if spec.StartAfter == nil {
+			continue
+		}
+		if _, ok := indexes[spec.StartAfter.Name]; !ok {
+			return fmt.Errorf("runner group #%d: startAfter refers to unknown runner group %s",
+				idx, spec.StartAfter.Name)
+		}
+	}
+
+	// NOTE: Each runner group has at most one dependency so that following
+	// the chain is enough to find cycle.
+	for idx, spec := range specs {
+		visited := map[int]bool{idx: true}
+		for cur := spec; cur.StartAfter != nil; {
+			next := indexes[cur.StartAfter.Name]
+			if visited[next] {
+				return fmt.Errorf("runner group #%d: startAfter has cycle", idx)
+			}
+			visited[next] = true
+			cur = specs[next]
+		}
+	}
+	return nil
+}
+
+// RunnerGroupStartBarrier is the state of runner group's start barrier,
+// which blocks runners until all the runners are ready.
+type RunnerGroupStartBarrier struct {
+	// Expected is the number of runners in runner group.
+	Expected int `json:"expected"`
+	// Arrived is the number of runners which are ready.
+	Arrived int `json:"arrived"`
+	// StartTime is when all the runners should start. It's set after the
+	// barrier has been released.
+	StartTime *time.Time `json:"startTime,omitempty"`
 }
 
 // RunnerGroupStatus represents current state of RunnerGroup.
@@ -54,4 +341,7 @@ const (
 	RunnerGroupStatusStateRunning = "running"
 	// RunnerGroupStatusStateFinished represents all runners finished.
 	RunnerGroupStatusStateFinished = "finished"
+	// RunnerGroupStatusStateAborted represents runner group has been
+	// aborted before all runners finished.
+	RunnerGroupStatusStateAborted = "aborted"
 )
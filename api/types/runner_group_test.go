@@ -0,0 +1,194 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestRunnerGroupSpecStartAfterUnmarshalFromYAML(t *testing.T) {
+	in := `
+count: 10
+name: readers
+startAfter:
+  name: writers
+  state: running
+startDelay: 5m
+`
+
+	spec := RunnerGroupSpec{}
+	require.NoError(t, yaml.Unmarshal([]byte(in), &spec))
+	assert.Equal(t, "readers", spec.Name)
+	assert.Equal(t, &RunnerGroupStartAfter{Name: "writers", State: "running"}, spec.StartAfter)
+	assert.Equal(t, 5*time.Minute, spec.StartDelay)
+	assert.NoError(t, spec.Validate())
+}
+
+func TestValidateRunnerGroupSpecs(t *testing.T) {
+	after := func(name string) *RunnerGroupStartAfter {
+		return &RunnerGroupStartAfter{Name: name}
+	}
+
+	for _, tc := range []struct {
+		name  string
+		specs []*RunnerGroupSpec
+		err   string
+	}{
+		{
+			name: "ok",
+			specs: []*RunnerGroupSpec{
+				{Name: "writers"},
+				{Name: "readers", StartAfter: after("writers")},
+				{StartAfter: after("readers"), StartDelay: time.Minute},
+			},
+		},
+		{
+			name:  "duplicate name",
+			specs: []*RunnerGroupSpec{{Name: "a"}, {Name: "a"}},
+			err:   "duplicate",
+		},
+		{
+			name:  "unknown dependency",
+			specs: []*RunnerGroupSpec{{Name: "a", StartAfter: after("b")}},
+			err:   "unknown runner group b",
+		},
+		{
+			name: "cycle",
+			specs: []*RunnerGroupSpec{
+				{Name: "a", StartAfter: after("c")},
+				{Name: "b", StartAfter: after("a")},
+				{Name: "c", StartAfter: after("b")},
+			},
+			err: "cycle",
+		},
+		{
+			name:  "invalid state",
+			specs: []*RunnerGroupSpec{{Name: "a"}, {StartAfter: &RunnerGroupStartAfter{Name: "a", State: "pending"}}},
+			err:   "startAfter.state",
+		},
+		{
+			name:  "negative delay",
+			specs: []*RunnerGroupSpec{{StartDelay: -time.Second}},
+			err:   "startDelay",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateRunnerGroupSpecs(tc.specs)
+			if tc.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.err)
+		})
+	}
+}
+
+func TestRunnerPodOverridesUnmarshalFromYAML(t *testing.T) {
+	in := `
+count: 10
+podOverrides:
+  resources:
+    requests:
+      cpu: "2"
+      memory: 4Gi
+    limits:
+      memory: 4Gi
+  tolerations:
+  - key: benchmark
+    operator: Exists
+    effect: NoSchedule
+  - key: dedicated
+    value: kperf
+    tolerationSeconds: 60
+  priorityClassName: high-priority
+  imagePullSecrets:
+  - private-registry
+  dataVolume: emptyDir
+`
+
+	spec := RunnerGroupSpec{}
+	require.NoError(t, yaml.Unmarshal([]byte(in), &spec))
+	require.NotNil(t, spec.PodOverrides)
+
+	o := spec.PodOverrides
+	assert.Equal(t, map[string]string{"cpu": "2", "memory": "4Gi"}, o.Resources.Requests)
+	assert.Equal(t, map[string]string{"memory": "4Gi"}, o.Resources.Limits)
+	require.Len(t, o.Tolerations, 2)
+	assert.Equal(t, "Exists", o.Tolerations[0].Operator)
+	assert.Equal(t, int64(60), *o.Tolerations[1].TolerationSeconds)
+	assert.Equal(t, "high-priority", o.PriorityClassName)
+	assert.Equal(t, []string{"private-registry"}, o.ImagePullSecrets)
+	assert.Equal(t, RunnerDataVolumeEmptyDir, o.DataVolume)
+	assert.NoError(t, spec.Validate())
+
+	o.Resources.Limits["cpu"] = "two"
+	assert.Error(t, spec.Validate())
+	delete(o.Resources.Limits, "cpu")
+
+	o.Tolerations[0].Value = "x"
+	assert.Error(t, spec.Validate())
+	o.Tolerations[0].Value = ""
+
+	o.DataVolume = "nfs"
+	assert.Error(t, spec.Validate())
+}
+
+func TestRunnerSpreadUnmarshalFromYAML(t *testing.T) {
+	in := `
+count: 10
+spread:
+  onePerNode: true
+  topologyConstraints:
+  - topologyKey: topology.kubernetes.io/zone
+    maxSkew: 2
+    whenUnsatisfiable: ScheduleAnyway
+`
+
+	spec := RunnerGroupSpec{}
+	require.NoError(t, yaml.Unmarshal([]byte(in), &spec))
+	require.NotNil(t, spec.Spread)
+	assert.True(t, spec.Spread.OnePerNode)
+	assert.Equal(t, []RunnerTopologyConstraint{
+		{
+			TopologyKey:       "topology.kubernetes.io/zone",
+			MaxSkew:           2,
+			WhenUnsatisfiable: "ScheduleAnyway",
+		},
+	}, spec.Spread.TopologyConstraints)
+	assert.NoError(t, spec.Validate())
+
+	spec.Spread.TopologyConstraints[0].WhenUnsatisfiable = "Never"
+	assert.Error(t, spec.Validate())
+
+	spec.Spread.TopologyConstraints[0] = RunnerTopologyConstraint{MaxSkew: 1}
+	assert.Error(t, spec.Validate())
+}
+
+func TestRunnerMetricsUnmarshalFromYAML(t *testing.T) {
+	in := `
+count: 10
+metrics:
+  port: 9090
+  scrapeAnnotations: true
+`
+
+	spec := RunnerGroupSpec{}
+	require.NoError(t, yaml.Unmarshal([]byte(in), &spec))
+	require.NotNil(t, spec.Metrics)
+	assert.Equal(t, RunnerMetrics{Port: 9090, ScrapeAnnotations: true}, *spec.Metrics)
+	assert.NoError(t, spec.Validate())
+
+	spec.Metrics.Port = 70000
+	assert.Error(t, spec.Validate())
+
+	spec = RunnerGroupSpec{}
+	require.NoError(t, yaml.Unmarshal([]byte("count: 10\n"), &spec))
+	assert.Nil(t, spec.Metrics)
+}
@@ -0,0 +1,39 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package types
+
+// RunnerSearchReport is the result of searching the maximum sustainable rate
+// under SLO thresholds.
+type RunnerSearchReport struct {
+	// Mode is the search strategy, ramp or binary.
+	Mode string `json:"mode"`
+	// MaxRate is the highest rate which passes all the thresholds. Zero
+	// means that no rate passes.
+	MaxRate float64 `json:"maxRate"`
+	// Steps is the curve of rate against latency, sorted by rate.
+	Steps []RunnerSearchStep `json:"steps"`
+	// Interrupted means search was cancelled, for instance, by SIGINT, and
+	// the report only covers the steps finished before that.
+	Interrupted bool `json:"interrupted,omitempty"`
+}
+
+// RunnerSearchStep is the result of running load profile at one rate.
+type RunnerSearchStep struct {
+	// Rate is the target requests per second.
+	Rate float64 `json:"rate"`
+	// AchievedRate is the actual requests per second.
+	AchievedRate float64 `json:"achievedRate"`
+	// Total is the number of requests.
+	Total int `json:"total"`
+	// Duration means the time of this step.
+	Duration string `json:"duration"`
+	// ErrorRatio is the ratio of failed requests.
+	ErrorRatio float64 `json:"errorRatio"`
+	// PercentileLatencies represents the latency distribution in seconds.
+	PercentileLatencies [][2]float64 `json:"percentileLatencies,omitempty"`
+	// Passed is true if this step passes all the thresholds.
+	Passed bool `json:"passed"`
+	// Reason shows why this step doesn't pass.
+	Reason string `json:"reason,omitempty"`
+}
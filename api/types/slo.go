@@ -0,0 +1,98 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+// SLO defines the thresholds which the benchmark result should meet.
+type SLO struct {
+	// P99Latencies defines the maximum p99 latency for requests.
+	P99Latencies []LatencyThreshold `json:"p99Latencies,omitempty" yaml:"p99Latencies,omitempty"`
+	// MaxErrorRatio is the maximum ratio of failed requests.
+	MaxErrorRatio *float64 `json:"maxErrorRatio,omitempty" yaml:"maxErrorRatio,omitempty"`
+	// MaxTooManyRequestsRatio is the maximum ratio of requests rejected
+	// with 429 code.
+	MaxTooManyRequestsRatio *float64 `json:"maxTooManyRequestsRatio,omitempty" yaml:"maxTooManyRequestsRatio,omitempty"`
+	// MinQPS is the minimum achieved requests per second (zero is no limit).
+	MinQPS float64 `json:"minQPS,omitempty" yaml:"minQPS,omitempty"`
+}
+
+// LatencyThreshold defines the maximum latency for a set of requests.
+type LatencyThreshold struct {
+	// URL matches the requests whose URL contains it. It requires
+	// latencies by URL in report, which are only recorded by runner with
+	// --latencies-by-url flag. The runners in runner group don't record
+	// them.
+	URL string `json:"url,omitempty" yaml:"url,omitempty"`
+	// Tag matches the requests with the tag.
+	//
+	// NOTE: Both URL and Tag are empty means all the requests.
+	Tag string `json:"tag,omitempty" yaml:"tag,omitempty"`
+	// Max is the maximum latency.
+	Max time.Duration `json:"max" yaml:"max"`
+}
+
+// SLOVerdict is the result of evaluating SLO.
+type SLOVerdict struct {
+	// Passed is true if all the checks passed.
+	Passed bool `json:"passed"`
+	// Checks stores the result of each threshold.
+	Checks []SLOCheck `json:"checks"`
+}
+
+// SLOCheck is the result of evaluating one threshold.
+type SLOCheck struct {
+	// Name is the name of threshold, for instance, errorRatio.
+	Name string `json:"name"`
+	// Threshold is the expected value.
+	Threshold float64 `json:"threshold"`
+	// Actual is the observed value.
+	Actual float64 `json:"actual"`
+	// Passed is true if the actual value meets threshold.
+	Passed bool `json:"passed"`
+	// Message shows detail if check fails.
+	Message string `json:"message,omitempty"`
+}
+
+// Validate verifies fields of SLO.
+func (slo SLO) Validate() error {
+	for idx, l := range slo.P99Latencies {
+		if l.Max <= 0 {
+			return fmt.Errorf("p99Latencies[%d]: max requires > 0: %v", idx, l.Max)
+		}
+		if l.URL != "" && l.Tag != "" {
+			return fmt.Errorf("p99Latencies[%d]: url and tag are mutually exclusive", idx)
+		}
+	}
+
+	if r := slo.MaxErrorRatio; r != nil && (*r < 0 || *r > 1) {
+		return fmt.Errorf("maxErrorRatio requires [0, 1]: %v", *r)
+	}
+
+	if r := slo.MaxTooManyRequestsRatio; r != nil && (*r < 0 || *r > 1) {
+		return fmt.Errorf("maxTooManyRequestsRatio requires [0, 1]: %v", *r)
+	}
+
+	if slo.MinQPS < 0 {
+		return fmt.Errorf("minQPS requires >= 0: %v", slo.MinQPS)
+	}
+	return nil
+}
+
+// ValidateLatenciesByURL returns error if there is threshold matching
+// requests by URL but latencies by URL are not recorded.
+func (slo SLO) ValidateLatenciesByURL(enabled bool) error {
+	if enabled {
+		return nil
+	}
+	for idx, l := range slo.P99Latencies {
+		if l.URL != "" {
+			return fmt.Errorf("p99Latencies[%d]: url %s requires latencies by URL, please use tag instead", idx, l.URL)
+		}
+	}
+	return nil
+}
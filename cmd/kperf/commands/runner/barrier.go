@@ -0,0 +1,101 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Azure/kperf/api/types"
+
+	"k8s.io/klog/v2"
+)
+
+// startBarrierRetryInterval is the interval to retry if the server is
+// unavailable.
+const startBarrierRetryInterval = 5 * time.Second
+
+// waitForStartBarrier blocks until the start barrier at barrierURL is
+// released and then sleeps until the common start time. It gives up waiting
+// after timeout so that runner can start anyway.
+func waitForStartBarrier(ctx context.Context, barrierURL string, timeout time.Duration) error {
+	u, err := url.Parse(barrierURL)
+	if err != nil {
+		return fmt.Errorf("invalid start barrier url %s: %w", barrierURL, err)
+	}
+	query := u.Query()
+	query.Set("wait", "true")
+	u.RawQuery = query.Encode()
+
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	klog.V(2).InfoS("Waiting for start barrier", "url", barrierURL, "timeout", timeout)
+	for {
+		state, err := getStartBarrier(waitCtx, u.String())
+		if err == nil && state.StartTime != nil {
+			klog.V(2).InfoS("Start barrier released", "arrived", state.Arrived,
+				"expected", state.Expected, "start-time", state.StartTime)
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Until(*state.StartTime)):
+			}
+			return nil
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if waitCtx.Err() != nil {
+			klog.Warningf("Start without other runners since start barrier isn't released after %v", timeout)
+			return nil
+		}
+
+		klog.V(2).ErrorS(err, "failed to wait for start barrier, retry", "url", barrierURL)
+		select {
+		case <-waitCtx.Done():
+		case <-time.After(startBarrierRetryInterval):
+		}
+	}
+}
+
+// getStartBarrier gets start barrier's state from server.
+func getStartBarrier(ctx context.Context, targetURL string) (*types.RunnerGroupStartBarrier, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", targetURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init GET request: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		herr := types.HTTPError{}
+		if err := json.Unmarshal(data, &herr); err != nil {
+			return nil, fmt.Errorf("unexpected http code %v", resp.Status)
+		}
+		return nil, fmt.Errorf("unexpected http code %v: %w", resp.Status, herr)
+	}
+
+	state := &types.RunnerGroupStartBarrier{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal start barrier: %w", err)
+	}
+	return state, nil
+}
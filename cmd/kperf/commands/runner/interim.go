@@ -0,0 +1,87 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package runner
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/Azure/kperf/metrics"
+	"github.com/Azure/kperf/request"
+
+	"k8s.io/klog/v2"
+)
+
+// startInterimUploader uploads interim report built from the statistics
+// gathered so far to interimURL every interval. It returns the option to
+// gather ResponseMetric's statistics and the function to stop uploading.
+//
+// NOTE: The interim report is best-effort. It isn't retried because the next
+// one supersedes it.
+func startInterimUploader(interimURL string, interval time.Duration) (_ metrics.ResponseMetricOpt, stop func()) {
+	snapshotter := metrics.NewSnapshotter()
+
+	var wg sync.WaitGroup
+	stopCh := make(chan struct{})
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-ticker.C:
+				uploadInterimReport(interimURL, snapshotter, interval)
+			}
+		}
+	}()
+
+	return metrics.WithSnapshotterOpt(snapshotter), func() {
+		close(stopCh)
+		wg.Wait()
+	}
+}
+
+// uploadInterimReport posts the statistics gathered so far once.
+func uploadInterimReport(interimURL string, snapshotter *metrics.Snapshotter, timeout time.Duration) {
+	stats, elapsed, ok := snapshotter.Snapshot()
+	if !ok {
+		return
+	}
+
+	// NOTE: Each request belongs to exactly one resource group.
+	total := 0
+	for _, s := range stats.StatsByResource {
+		total += s.Total
+	}
+
+	report := buildRunnerMetricReport(false, &request.Result{
+		ResponseStats: stats,
+		Duration:      elapsed,
+		Total:         total,
+	})
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		klog.V(2).ErrorS(err, "failed to marshal interim report")
+		return
+	}
+	sum := sha256.Sum256(data)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if _, err := postRunnerMetricReport(ctx, interimURL, data, hex.EncodeToString(sum[:])); err != nil {
+		klog.V(2).ErrorS(err, "failed to upload interim report", "url", interimURL)
+	}
+}
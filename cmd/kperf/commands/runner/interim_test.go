@@ -0,0 +1,61 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package runner
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/kperf/api/types"
+	"github.com/Azure/kperf/metrics"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterimUploader(t *testing.T) {
+	var mu sync.Mutex
+	var received []*types.RunnerMetricReport
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := &types.RunnerMetricReport{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(report))
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		received = append(received, report)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	opt, stop := startInterimUploader(srv.URL, 10*time.Millisecond)
+
+	m := metrics.NewResponseMetric(opt)
+	req := metrics.RequestInfo{Verb: "LIST", Resource: "pods", Scope: "cluster", Tag: "list-pods"}
+	for i := 0; i < 3; i++ {
+		m.ObserveDispatch(req, 0)
+		m.ObserveLatency(req, 0.1)
+	}
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	got := received[len(received)-1]
+	assert.Equal(t, 3, got.Total)
+	assert.False(t, got.Interrupted)
+	require.Contains(t, got.StatsByTag, "list-pods")
+	assert.Equal(t, 3, got.StatsByTag["list-pods"].Total)
+}
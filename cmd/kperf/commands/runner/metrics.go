@@ -0,0 +1,53 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/Azure/kperf/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"k8s.io/klog/v2"
+)
+
+// startMetricsServer exposes Prometheus metrics at /metrics on addr. It
+// returns the option to feed ResponseMetric's observations into Prometheus
+// metrics and the function to stop the server.
+func startMetricsServer(addr string) (_ metrics.ResponseMetricOpt, stop func(), _ error) {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	prom, err := metrics.NewPrometheusMetrics(reg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+
+	//nolint:gosec
+	srv := &http.Server{Handler: mux}
+	go func() {
+		if err := srv.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			klog.ErrorS(err, "failed to serve metrics", "addr", addr)
+		}
+	}()
+
+	klog.V(2).InfoS("Serving metrics", "addr", lis.Addr().String())
+	return metrics.WithPrometheusMetricsOpt(prom), func() { srv.Close() }, nil
+}
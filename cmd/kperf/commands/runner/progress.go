@@ -0,0 +1,123 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Azure/kperf/metrics"
+
+	"golang.org/x/term"
+)
+
+// startProgressPrinter prints progress to stderr every interval. It redraws
+// a compact table if stderr is a terminal, otherwise it prints one line each
+// time. It returns the option to feed ResponseMetric's observations into
+// progress and the function to stop printing.
+func startProgressPrinter(interval time.Duration, total int) (_ metrics.ResponseMetricOpt, stop func()) {
+	progress := metrics.NewProgress()
+
+	var printer progressPrinter = &linePrinter{w: os.Stderr, total: total}
+	if term.IsTerminal(int(os.Stderr.Fd())) {
+		printer = &tablePrinter{w: os.Stderr, total: total}
+	}
+
+	var wg sync.WaitGroup
+	stopCh := make(chan struct{})
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stopCh:
+				return
+			case now := <-ticker.C:
+				printer.print(progress.Next(now))
+			}
+		}
+	}()
+
+	return metrics.WithProgressOpt(progress), func() {
+		close(stopCh)
+		wg.Wait()
+	}
+}
+
+// progressPrinter prints ProgressSnapshot.
+type progressPrinter interface {
+	print(metrics.ProgressSnapshot)
+}
+
+// linePrinter prints progress in one line, for instance,
+//
+// progress: elapsed=1m0s requests=6000/10000 qps=100.00 inflight=10 p50=0.0120s p99=0.0450s errors=http/403:12
+type linePrinter struct {
+	w     io.Writer
+	total int
+}
+
+func (p *linePrinter) print(s metrics.ProgressSnapshot) {
+	fmt.Fprintf(p.w, "progress: elapsed=%v requests=%d/%d qps=%.2f inflight=%d p50=%.4fs p99=%.4fs errors=%s\n",
+		s.Elapsed.Round(time.Second), s.Finished, p.total, s.QPS, s.InFlight, s.P50, s.P99,
+		formatProgressErrors(s.ErrorStats, ":", ","))
+}
+
+// tablePrinter redraws progress as a table in place.
+type tablePrinter struct {
+	w     io.Writer
+	total int
+	// lines is the number of lines printed last time.
+	lines int
+}
+
+func (p *tablePrinter) print(s metrics.ProgressSnapshot) {
+	buf := &bytes.Buffer{}
+
+	tw := tabwriter.NewWriter(buf, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "ELAPSED\tREQUESTS\tQPS\tINFLIGHT\tP50\tP99\tERRORS")
+	fmt.Fprintf(tw, "%v\t%d/%d\t%.2f\t%d\t%.4fs\t%.4fs\t%s\n",
+		s.Elapsed.Round(time.Second), s.Finished, p.total, s.QPS, s.InFlight, s.P50, s.P99,
+		formatProgressErrors(s.ErrorStats, "=", " "))
+	tw.Flush()
+
+	if p.lines > 0 {
+		// NOTE: Move cursor up and clear the previous table.
+		fmt.Fprintf(p.w, "\033[%dA\033[J", p.lines)
+	}
+	p.lines = strings.Count(buf.String(), "\n")
+	_, _ = p.w.Write(buf.Bytes())
+}
+
+// formatProgressErrors returns sorted error stats, for instance,
+// "http/403:12,http/429:3". It returns "-" if there is no error.
+func formatProgressErrors(errorStats map[string]int64, kvSep, sep string) string {
+	if len(errorStats) == 0 {
+		return "-"
+	}
+
+	keys := make([]string, 0, len(errorStats))
+	for k := range errorStats {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s%s%d", k, kvSep, errorStats[k]))
+	}
+	return strings.Join(pairs, sep)
+}
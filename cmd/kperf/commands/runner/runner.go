@@ -6,10 +6,12 @@ package runner
 import (
 	"context"
 	"encoding/json"
-
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
+	"time"
 
 	"github.com/Azure/kperf/api/types"
 	"github.com/Azure/kperf/cmd/kperf/commands/utils"
@@ -18,6 +20,7 @@ import (
 
 	"github.com/urfave/cli"
 	"gopkg.in/yaml.v2"
+	"k8s.io/klog/v2"
 )
 
 // Command represents runner subcommand.
@@ -26,76 +29,200 @@ var Command = cli.Command{
 	Usage: "Setup benchmark to kube-apiserver from one endpoint",
 	Subcommands: []cli.Command{
 		runCommand,
+		searchCommand,
+	},
+}
+
+// loadProfileFlags is used to load and override load profile. It's shared by
+// subcommands which run load profile.
+var loadProfileFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "kubeconfig",
+		Usage: "Path to the kubeconfig file",
+		Value: utils.DefaultKubeConfigPath,
+	},
+	cli.IntFlag{
+		Name:  "client",
+		Usage: "Total number of HTTP clients",
+		Value: 1,
+	},
+	cli.StringFlag{
+		Name:     "config",
+		Usage:    "Path to the configuration file",
+		Required: true,
+	},
+	cli.IntFlag{
+		Name:  "conns",
+		Usage: "Total number of connections. It can override corresponding value defined by --config",
+		Value: 1,
+	},
+	cli.StringFlag{
+		Name:  "content-type",
+		Usage: fmt.Sprintf("Content type (%v or %v)", types.ContentTypeJSON, types.ContentTypeProtobuffer),
+		Value: string(types.ContentTypeJSON),
+	},
+	cli.Float64Flag{
+		Name:  "rate",
+		Usage: "Maximum requests per second (Zero means no limitation). It can override corresponding value defined by --config",
+	},
+	cli.IntFlag{
+		Name:  "total",
+		Usage: "Total number of requests. It can override corresponding value defined by --config",
+		Value: 1000,
+	},
+	cli.StringFlag{
+		Name:  "user-agent",
+		Usage: "User Agent",
+	},
+	cli.BoolFlag{
+		Name:  "disable-http2",
+		Usage: "Disable HTTP2 protocol",
+	},
+	cli.IntFlag{
+		Name:  "max-retries",
+		Usage: "Retry request after receiving 429 http code (<=0 means no retry)",
+		Value: 0,
+	},
+	cli.IntFlag{
+		Name:  "churn-every-requests",
+		Usage: "Rebuild client's connection after sending the number of requests (Zero means no churn). It can override corresponding value defined by --config",
+	},
+	cli.DurationFlag{
+		Name:  "churn-interval",
+		Usage: "Rebuild client's connection periodically (Zero means no churn). It can override corresponding value defined by --config",
+	},
+	cli.DurationFlag{
+		Name:  "churn-jitter",
+		Usage: "Add random jitter to --churn-interval. It can override corresponding value defined by --config",
+	},
+	cli.DurationFlag{
+		Name:  "reconnect-storm-after",
+		Usage: "Force all the connections to reconnect at once after the duration (Zero means disabled). It can override corresponding value defined by --config",
+	},
+	cli.DurationFlag{
+		Name:  "time-series-interval",
+		Usage: "Group statistics by the interval in result (Zero means disabled). It can override corresponding value defined by --config",
 	},
 }
 
 var runCommand = cli.Command{
 	Name:  "run",
 	Usage: "run a benchmark test to kube-apiserver",
-	Flags: []cli.Flag{
-		cli.StringFlag{
-			Name:  "kubeconfig",
-			Usage: "Path to the kubeconfig file",
-			Value: utils.DefaultKubeConfigPath,
-		},
-		cli.IntFlag{
-			Name:  "client",
-			Usage: "Total number of HTTP clients",
-			Value: 1,
-		},
-		cli.StringFlag{
-			Name:     "config",
-			Usage:    "Path to the configuration file",
-			Required: true,
-		},
-		cli.IntFlag{
-			Name:  "conns",
-			Usage: "Total number of connections. It can override corresponding value defined by --config",
-			Value: 1,
-		},
-		cli.StringFlag{
-			Name:  "content-type",
-			Usage: fmt.Sprintf("Content type (%v or %v)", types.ContentTypeJSON, types.ContentTypeProtobuffer),
-			Value: string(types.ContentTypeJSON),
-		},
-		cli.Float64Flag{
-			Name:  "rate",
-			Usage: "Maximum requests per second (Zero means no limitation). It can override corresponding value defined by --config",
-		},
-		cli.IntFlag{
-			Name:  "total",
-			Usage: "Total number of requests. It can override corresponding value defined by --config",
-			Value: 1000,
-		},
-		cli.StringFlag{
-			Name:  "user-agent",
-			Usage: "User Agent",
-		},
-		cli.BoolFlag{
-			Name:  "disable-http2",
-			Usage: "Disable HTTP2 protocol",
-		},
-		cli.IntFlag{
-			Name:  "max-retries",
-			Usage: "Retry request after receiving 429 http code (<=0 means no retry)",
-			Value: 0,
-		},
-		cli.StringFlag{
-			Name:  "result",
-			Usage: "Path to the file which stores results",
-		},
-		cli.BoolFlag{
-			Name:  "raw-data",
-			Usage: "show raw letencies data in result",
+	Flags: append(
+		[]cli.Flag{
+			cli.StringFlag{
+				Name:  "result",
+				Usage: "Path to the file which stores results",
+			},
+			cli.BoolFlag{
+				Name:  "raw-data",
+				Usage: "show raw data, including sampled errors and latency histograms by URL, in result",
+			},
+			cli.IntFlag{
+				Name:  "error-samples",
+				Usage: "Maximum number of sampled errors in result. Errors are counted by type, code and tag regardless of it",
+				Value: metrics.DefaultErrorSamples,
+			},
+			cli.StringFlag{
+				Name:  "slo",
+				Usage: "Path to the SLO file. It can override SLO defined by --config",
+			},
+			cli.BoolTFlag{
+				Name:  "fail-on-slo-violation",
+				Usage: "Exit with non-zero code if the result violates SLO",
+			},
+			cli.BoolFlag{
+				Name:  "latencies-by-url",
+				Usage: "Show percentile latencies for each request URL in result",
+			},
+			cli.BoolFlag{
+				Name:  "apiserver-metrics",
+				Usage: "Scrape /metrics from each kube-apiserver replica before and after benchmark and show the change in result",
+			},
+			cli.StringFlag{
+				Name:  "start-barrier-url",
+				Usage: "Wait for other runners at the start barrier URL served by runner group server before benchmark (Empty means disabled)",
+			},
+			cli.DurationFlag{
+				Name:  "start-barrier-timeout",
+				Usage: "Start anyway if the start barrier isn't released in time",
+				Value: 10 * time.Minute,
+			},
+			cli.StringFlag{
+				Name:  "upload-url",
+				Usage: "Upload report to the URL served by runner group server after benchmark, even if benchmark was interrupted or failed (Empty means disabled)",
+			},
+			cli.DurationFlag{
+				Name:  "upload-timeout",
+				Usage: "Give up uploading report if it doesn't succeed in time. Only valid when --upload-url",
+				Value: 10 * time.Minute,
+			},
+			cli.StringFlag{
+				Name:  "interim-upload-url",
+				Usage: "Upload interim report built from the statistics gathered so far to the URL served by runner group server every --interim-interval during benchmark (Empty means disabled)",
+			},
+			cli.DurationFlag{
+				Name:  "interim-interval",
+				Usage: "The interval to upload interim report. Only valid when --interim-upload-url",
+				Value: 30 * time.Second,
+			},
+			cli.DurationFlag{
+				Name:  "progress-interval",
+				Usage: "Print progress to stderr every interval during benchmark (Zero means disabled)",
+				Value: 10 * time.Second,
+			},
+			cli.StringFlag{
+				Name:  "metrics-addr",
+				Usage: "Expose Prometheus metrics at /metrics on the address during benchmark, for instance, :8080 (Empty means disabled)",
+			},
+			cli.StringFlag{
+				Name:  "otlp-endpoint",
+				Usage: "Export a span for each request to the OTLP/HTTP endpoint, for instance, http://localhost:4318 (Empty means disabled)",
+			},
+			cli.Float64Flag{
+				Name:  "trace-sample-ratio",
+				Usage: "The fraction of requests to trace in [0, 1]. It only works with --otlp-endpoint",
+				Value: 1,
+			},
 		},
-	},
-	Action: func(cliCtx *cli.Context) error {
+		loadProfileFlags...,
+	),
+	Action: func(cliCtx *cli.Context) (retErr error) {
+		// NOTE: Upload the report recording failure on any error so that
+		// runner group server knows why runner failed. It's skipped once
+		// runner starts uploading the benchmark's report.
+		skipFailureReport := false
+		defer func() {
+			if retErr != nil && !skipFailureReport {
+				retErr = uploadRunnerFailure(cliCtx, retErr)
+			}
+		}()
+
 		kubeCfgPath := cliCtx.String("kubeconfig")
 
+		// NOTE: Load SLO before benchmark so that invalid SLO file doesn't
+		// waste the whole run.
+		var slo *types.SLO
+		if sloPath := cliCtx.String("slo"); sloPath != "" {
+			var err error
+			slo, err = utils.LoadSLOFromFile(sloPath)
+			if err != nil {
+				return err
+			}
+		}
+
 		profileCfg, err := loadConfig(cliCtx)
 		if err != nil {
 			return err
 		}
+		if slo == nil {
+			slo = profileCfg.SLO
+		}
+		if slo != nil {
+			if err := slo.ValidateLatenciesByURL(cliCtx.Bool("latencies-by-url")); err != nil {
+				return fmt.Errorf("invalid slo: %w", err)
+			}
+		}
 
 		clientNum := profileCfg.Spec.Conns
 		restClis, err := request.NewClients(kubeCfgPath,
@@ -104,46 +231,186 @@ var runCommand = cli.Command{
 			request.WithClientQPSOpt(profileCfg.Spec.Rate),
 			request.WithClientContentTypeOpt(profileCfg.Spec.ContentType),
 			request.WithClientDisableHTTP2Opt(profileCfg.Spec.DisableHTTP2),
+			request.WithClientConnChurnOpt(profileCfg.Spec.ConnChurn),
 		)
 		if err != nil {
 			return err
 		}
 
-		stats, err := request.Schedule(context.TODO(), &profileCfg.Spec, restClis)
+		flowControlCli, err := request.NewFlowControlClient(kubeCfgPath, cliCtx.String("user-agent"))
 		if err != nil {
 			return err
 		}
 
-		var f *os.File = os.Stdout
-		outputFilePath := cliCtx.String("result")
-		if outputFilePath != "" {
-			outputFileDir := filepath.Dir(outputFilePath)
+		if endpoint := cliCtx.String("otlp-endpoint"); endpoint != "" {
+			shutdown, err := setupTracing(context.TODO(), endpoint,
+				cliCtx.Float64("trace-sample-ratio"), cliCtx.String("user-agent"))
+			if err != nil {
+				return err
+			}
+			defer shutdown()
+		}
+
+		metricOpts := []metrics.ResponseMetricOpt{
+			metrics.WithLatenciesByURLOpt(cliCtx.Bool("latencies-by-url")),
+			metrics.WithErrorSamplesOpt(cliCtx.Int("error-samples")),
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			// NOTE: Restore default behavior so that the second signal
+			// terminates process if draining takes too long.
+			stop()
+		}()
+
+		if barrierURL := cliCtx.String("start-barrier-url"); barrierURL != "" {
+			if err := waitForStartBarrier(ctx, barrierURL, cliCtx.Duration("start-barrier-timeout")); err != nil {
+				return fmt.Errorf("failed to wait for start barrier: %w", err)
+			}
+		}
+
+		// NOTE: Start progress printer, interim uploader and metrics server
+		// after start barrier so that elapsed time and rates don't include
+		// time spent on waiting for other runners.
+		if interval := cliCtx.Duration("progress-interval"); interval > 0 {
+			opt, stop := startProgressPrinter(interval, profileCfg.Spec.Total)
+			defer stop()
+
+			metricOpts = append(metricOpts, opt)
+		}
+		if interimURL := cliCtx.String("interim-upload-url"); interimURL != "" {
+			if interval := cliCtx.Duration("interim-interval"); interval > 0 {
+				opt, stop := startInterimUploader(interimURL, interval)
+				defer stop()
 
-			_, err = os.Stat(outputFileDir)
-			if err != nil && os.IsNotExist(err) {
-				err = os.MkdirAll(outputFileDir, 0750)
+				metricOpts = append(metricOpts, opt)
 			}
+		}
+		if addr := cliCtx.String("metrics-addr"); addr != "" {
+			opt, stop, err := startMetricsServer(addr)
 			if err != nil {
-				return fmt.Errorf("failed to ensure output's dir %s: %w", outputFileDir, err)
+				return err
 			}
+			defer stop()
+
+			metricOpts = append(metricOpts, opt)
+		}
 
-			f, err = os.Create(outputFilePath)
+		var scraper *request.APIServerMetricsScraper
+		var apiserverMetricsBefore map[string]*metrics.APIServerMetricsSnapshot
+		if cliCtx.Bool("apiserver-metrics") {
+			scraper, err = request.NewAPIServerMetricsScraper(kubeCfgPath, cliCtx.String("user-agent"))
 			if err != nil {
-				return err
+				return fmt.Errorf("failed to create kube-apiserver's metrics scraper: %w", err)
 			}
-			defer f.Close()
+			apiserverMetricsBefore = scraper.Scrape(context.TODO())
+		}
+
+		stats, err := request.Schedule(ctx, &profileCfg.Spec, restClis, flowControlCli, metricOpts...)
+		if err != nil {
+			return fmt.Errorf("failed to run benchmark: %w", err)
+		}
+
+		f, done, err := openResultFile(cliCtx.String("result"))
+		if err != nil {
+			return err
 		}
+		defer done()
 
 		rawDataFlagIncluded := cliCtx.Bool("raw-data")
-		err = printResponseStats(f, rawDataFlagIncluded, stats)
+		output := buildRunnerMetricReport(rawDataFlagIncluded, stats)
+		if interval := profileCfg.Spec.TimeSeriesInterval; interval > 0 {
+			output.TimeSeriesInterval = interval.String()
+		}
+		if scraper != nil {
+			output.APIServerMetrics = request.DiffAPIServerMetrics(apiserverMetricsBefore, scraper.Scrape(context.TODO()))
+		}
+		if slo != nil {
+			output.Verdict = metrics.EvaluateSLO(slo, output)
+		}
+
+		err = printRunnerMetricReport(f, output)
 		if err != nil {
 			return fmt.Errorf("error while printing response stats: %w", err)
 		}
 
+		uploadURL := cliCtx.String("upload-url")
+		if uploadURL != "" {
+			// NOTE: The server has got the report or is unreachable.
+			// Either way, the failure report doesn't help.
+			skipFailureReport = true
+
+			// NOTE: Don't use ctx since it has been cancelled if benchmark
+			// was interrupted.
+			err = uploadRunnerMetricReport(context.Background(), uploadURL, output, cliCtx.Duration("upload-timeout"))
+			if err != nil {
+				return err
+			}
+		}
+
+		if output.Interrupted {
+			// NOTE: The runner group server has got the partial report
+			// marked interrupted. Exit with zero code so that runner's pod
+			// isn't counted as failed.
+			if uploadURL != "" {
+				klog.Warning("Benchmark was interrupted, partial report has been uploaded")
+				return nil
+			}
+			return fmt.Errorf("benchmark was interrupted, partial report has been written")
+		}
+		if cliCtx.BoolT("fail-on-slo-violation") {
+			return utils.SLOViolationError(output.Verdict)
+		}
 		return nil
 	},
 }
 
+// uploadRunnerFailure uploads the report recording err if --upload-url is set
+// so that runner group server knows why runner failed. It always returns err
+// so that runner exits with non-zero code.
+func uploadRunnerFailure(cliCtx *cli.Context, err error) error {
+	uploadURL := cliCtx.String("upload-url")
+	if uploadURL == "" {
+		return err
+	}
+
+	report := &types.RunnerMetricReport{
+		Duration: (0 * time.Second).String(),
+		Error:    err.Error(),
+	}
+	// NOTE: Don't use ctx since it might have been cancelled.
+	if uerr := uploadRunnerMetricReport(context.Background(), uploadURL, report, cliCtx.Duration("upload-timeout")); uerr != nil {
+		return fmt.Errorf("%w (%v)", err, uerr)
+	}
+	return err
+}
+
+// openResultFile creates the file to store results. It returns stdout if the
+// path is empty.
+func openResultFile(outputFilePath string) (_ *os.File, _done func(), _ error) {
+	if outputFilePath == "" {
+		return os.Stdout, func() {}, nil
+	}
+
+	outputFileDir := filepath.Dir(outputFilePath)
+
+	_, err := os.Stat(outputFileDir)
+	if err != nil && os.IsNotExist(err) {
+		err = os.MkdirAll(outputFileDir, 0750)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to ensure output's dir %s: %w", outputFileDir, err)
+	}
+
+	f, err := os.Create(outputFilePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, func() { f.Close() }, nil
+}
+
 // loadConfig loads and validates the config.
 func loadConfig(cliCtx *cli.Context) (*types.LoadProfile, error) {
 	var profileCfg types.LoadProfile
@@ -181,6 +448,10 @@ func loadConfig(cliCtx *cli.Context) (*types.LoadProfile, error) {
 	if v := "max-retries"; cliCtx.IsSet(v) {
 		profileCfg.Spec.MaxRetries = cliCtx.Int(v)
 	}
+	if v := "time-series-interval"; cliCtx.IsSet(v) {
+		profileCfg.Spec.TimeSeriesInterval = cliCtx.Duration(v)
+	}
+	overrideConnChurn(cliCtx, &profileCfg.Spec)
 
 	if err := profileCfg.Validate(); err != nil {
 		return nil, err
@@ -188,36 +459,95 @@ func loadConfig(cliCtx *cli.Context) (*types.LoadProfile, error) {
 	return &profileCfg, nil
 }
 
-// printResponseStats prints types.RunnerMetricReport into underlying file.
-func printResponseStats(f *os.File, rawDataFlagIncluded bool, stats *request.Result) error {
-	output := types.RunnerMetricReport{
+// overrideConnChurn overrides ConnChurn setting by flags.
+func overrideConnChurn(cliCtx *cli.Context, spec *types.LoadProfileSpec) {
+	churn := types.ConnChurn{}
+	if spec.ConnChurn != nil {
+		churn = *spec.ConnChurn
+	}
+
+	changed := false
+	if v := "churn-every-requests"; cliCtx.IsSet(v) {
+		churn.EveryRequests = cliCtx.Int(v)
+		changed = true
+	}
+	if v := "churn-interval"; cliCtx.IsSet(v) {
+		churn.Interval = cliCtx.Duration(v)
+		changed = true
+	}
+	if v := "churn-jitter"; cliCtx.IsSet(v) {
+		churn.Jitter = cliCtx.Duration(v)
+		changed = true
+	}
+	if v := "reconnect-storm-after"; cliCtx.IsSet(v) {
+		churn.StormAfter = cliCtx.Duration(v)
+		changed = true
+	}
+
+	if changed {
+		spec.ConnChurn = &churn
+	}
+}
+
+// buildRunnerMetricReport builds types.RunnerMetricReport from result.
+//
+// NOTE: The histograms in stats, priority levels and time series are always
+// kept so that runner group server can merge percentiles from runners'
+// reports. The rawDataFlagIncluded only controls debug details.
+func buildRunnerMetricReport(rawDataFlagIncluded bool, stats *request.Result) *types.RunnerMetricReport {
+	output := &types.RunnerMetricReport{
 		Total:              stats.Total,
-		ErrorStats:         metrics.BuildErrorStatsGroupByType(stats.Errors),
+		ErrorStats:         stats.ErrorStats,
+		ErrorCounts:        stats.ErrorCounts,
 		Duration:           stats.Duration.String(),
 		TotalReceivedBytes: stats.TotalReceivedBytes,
+		StatsByTag:         stats.StatsByTag,
+		StatsByResource:    stats.StatsByResource,
+		SelfStats:          stats.SelfStats,
+		Warnings:           stats.Warnings,
+		Interrupted:        stats.Interrupted,
+	}
 
-		PercentileLatenciesByURL: map[string][][2]float64{},
+	// NOTE: Each request belongs to exactly one resource group.
+	histograms := make([]*types.LatencyHistogram, 0, len(stats.StatsByResource))
+	for _, s := range stats.StatsByResource {
+		histograms = append(histograms, s.Histogram)
 	}
+	output.PercentileLatencies = metrics.MergeHistograms(histograms...).PercentileLatencies()
 
-	total := 0
-	for _, latencies := range stats.LatenciesByURL {
-		total += len(latencies)
+	for _, group := range []map[string]*types.RequestStats{output.StatsByTag, output.StatsByResource} {
+		for _, s := range group {
+			s.PercentileLatencies = metrics.MergeHistograms(s.Histogram).PercentileLatencies()
+		}
 	}
-	latencies := make([]float64, 0, total)
-	for _, l := range stats.LatenciesByURL {
-		latencies = append(latencies, l...)
+
+	if len(stats.HistogramsByURL) > 0 {
+		output.PercentileLatenciesByURL = make(map[string][][2]float64, len(stats.HistogramsByURL))
+		for u, h := range stats.HistogramsByURL {
+			output.PercentileLatenciesByURL[u] = metrics.MergeHistograms(h).PercentileLatencies()
+		}
 	}
-	output.PercentileLatencies = metrics.BuildPercentileLatencies(latencies)
 
-	for u, l := range stats.LatenciesByURL {
-		output.PercentileLatenciesByURL[u] = metrics.BuildPercentileLatencies(l)
+	output.PriorityLevels = stats.PriorityLevels
+	for _, pl := range output.PriorityLevels {
+		pl.PercentileLatencies = metrics.MergeHistograms(pl.Histogram).PercentileLatencies()
+	}
+
+	output.TimeSeries = stats.TimeSeries
+	for idx := range output.TimeSeries {
+		b := &output.TimeSeries[idx]
+		b.PercentileLatencies = metrics.MergeHistograms(b.Histogram).PercentileLatencies()
 	}
 
 	if rawDataFlagIncluded {
-		output.LatenciesByURL = stats.LatenciesByURL
+		output.HistogramsByURL = stats.HistogramsByURL
 		output.Errors = stats.Errors
 	}
+	return output
+}
 
+// printRunnerMetricReport prints types.RunnerMetricReport into underlying file.
+func printRunnerMetricReport(f *os.File, output *types.RunnerMetricReport) error {
 	encoder := json.NewEncoder(f)
 	encoder.SetIndent("", "  ")
 
@@ -0,0 +1,157 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/Azure/kperf/request"
+
+	"github.com/urfave/cli"
+)
+
+var searchCommand = cli.Command{
+	Name:  "search",
+	Usage: "search the maximum sustainable rate of load profile under SLO thresholds",
+	Flags: append(
+		[]cli.Flag{
+			cli.StringFlag{
+				Name:  "mode",
+				Usage: fmt.Sprintf("Search strategy (%v or %v)", request.SearchModeBinary, request.SearchModeRamp),
+				Value: string(request.SearchModeBinary),
+			},
+			cli.Float64Flag{
+				Name:  "min-rate",
+				Usage: "The lower bound of requests per second",
+				Value: 10,
+			},
+			cli.Float64Flag{
+				Name:     "max-rate",
+				Usage:    "The upper bound of requests per second",
+				Required: true,
+			},
+			cli.Float64Flag{
+				Name:  "step",
+				Usage: "The rate increment for each step. Only valid when --mode=ramp",
+				Value: 10,
+			},
+			cli.Float64Flag{
+				Name:  "precision",
+				Usage: "Stop searching when the range of rate is narrower than it. Only valid when --mode=binary",
+				Value: 5,
+			},
+			cli.DurationFlag{
+				Name:  "step-duration",
+				Usage: "The duration of each step. The total number of requests in each step is rate * duration",
+				Value: time.Minute,
+			},
+			cli.DurationFlag{
+				Name:  "cooldown",
+				Usage: "The pause between two steps",
+				Value: 10 * time.Second,
+			},
+			cli.DurationFlag{
+				Name:  "p99-threshold",
+				Usage: "The maximum p99 latency (Zero means no limitation)",
+				Value: time.Second,
+			},
+			cli.Float64Flag{
+				Name:  "error-ratio-threshold",
+				Usage: "The maximum ratio of failed requests",
+				Value: 0.01,
+			},
+			cli.Float64Flag{
+				Name:  "min-achieved-ratio",
+				Usage: "The minimum ratio of achieved rate to target rate",
+				Value: 0.9,
+			},
+			cli.StringFlag{
+				Name:  "result",
+				Usage: "Path to the file which stores results",
+			},
+		},
+		loadProfileFlags...,
+	),
+	Action: func(cliCtx *cli.Context) error {
+		kubeCfgPath := cliCtx.String("kubeconfig")
+
+		profileCfg, err := loadConfig(cliCtx)
+		if err != nil {
+			return err
+		}
+
+		searchCfg := request.SearchConfig{
+			Mode:                request.SearchMode(cliCtx.String("mode")),
+			MinRate:             cliCtx.Float64("min-rate"),
+			MaxRate:             cliCtx.Float64("max-rate"),
+			Step:                cliCtx.Float64("step"),
+			Precision:           cliCtx.Float64("precision"),
+			StepDuration:        cliCtx.Duration("step-duration"),
+			Cooldown:            cliCtx.Duration("cooldown"),
+			P99Threshold:        cliCtx.Duration("p99-threshold"),
+			ErrorRatioThreshold: cliCtx.Float64("error-ratio-threshold"),
+			MinAchievedRatio:    cliCtx.Float64("min-achieved-ratio"),
+		}
+		if err := searchCfg.Validate(); err != nil {
+			return err
+		}
+
+		// NOTE: The client-side rate limiter should not be the
+		// bottleneck when searching up to the maximum rate.
+		restClis, err := request.NewClients(kubeCfgPath,
+			profileCfg.Spec.Conns,
+			request.WithClientUserAgentOpt(cliCtx.String("user-agent")),
+			request.WithClientQPSOpt(searchCfg.MaxRate),
+			request.WithClientContentTypeOpt(profileCfg.Spec.ContentType),
+			request.WithClientDisableHTTP2Opt(profileCfg.Spec.DisableHTTP2),
+			request.WithClientConnChurnOpt(profileCfg.Spec.ConnChurn),
+		)
+		if err != nil {
+			return err
+		}
+
+		flowControlCli, err := request.NewFlowControlClient(kubeCfgPath, cliCtx.String("user-agent"))
+		if err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			// NOTE: Restore default behavior so that the second signal
+			// terminates process if draining takes too long.
+			stop()
+		}()
+
+		report, err := request.Search(ctx, &profileCfg.Spec, restClis, flowControlCli, searchCfg)
+		if err != nil {
+			return err
+		}
+
+		f, done, err := openResultFile(cliCtx.String("result"))
+		if err != nil {
+			return err
+		}
+		defer done()
+
+		encoder := json.NewEncoder(f)
+		encoder.SetIndent("", "  ")
+
+		if err := encoder.Encode(report); err != nil {
+			return fmt.Errorf("failed to encode json: %w", err)
+		}
+
+		if report.Interrupted {
+			return fmt.Errorf("search was interrupted, partial report has been written")
+		}
+		return nil
+	},
+}
@@ -0,0 +1,54 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package runner
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"k8s.io/klog/v2"
+)
+
+// setupTracing exports spans of requests to the OTLP/HTTP endpoint, for
+// instance, http://localhost:4318. It returns the function to flush spans.
+func setupTracing(ctx context.Context, endpoint string, sampleRatio float64, userAgent string) (shutdown func(), _ error) {
+	if sampleRatio < 0 || sampleRatio > 1 {
+		return nil, fmt.Errorf("trace sample ratio requires [0, 1]: %v", sampleRatio)
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otlp trace exporter: %w", err)
+	}
+
+	attrs := []attribute.KeyValue{attribute.String("service.name", "kperf-runner")}
+	if userAgent != "" {
+		attrs = append(attrs, attribute.String("service.instance.id", userAgent))
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attrs...)),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	)
+
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		if err := tp.Shutdown(ctx); err != nil {
+			klog.ErrorS(err, "failed to flush spans", "endpoint", endpoint)
+		}
+	}, nil
+}
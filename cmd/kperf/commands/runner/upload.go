@@ -0,0 +1,107 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package runner
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Azure/kperf/api/types"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	// uploadInitialBackoff is the first interval to retry upload.
+	uploadInitialBackoff = time.Second
+	// uploadMaxBackoff is the maximum interval to retry upload.
+	uploadMaxBackoff = 30 * time.Second
+)
+
+// uploadRunnerMetricReport uploads report to runner group server. It retries
+// with exponential backoff on network errors, checksum mismatch and 5xx, and
+// gives up after timeout. The report has been uploaded if server returns 409.
+func uploadRunnerMetricReport(ctx context.Context, uploadURL string, report *types.RunnerMetricReport, timeout time.Duration) error {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+
+	uploadCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	backoff := uploadInitialBackoff
+	for attempt := 1; ; attempt++ {
+		retry, err := postRunnerMetricReport(uploadCtx, uploadURL, data, checksum)
+		if err == nil {
+			klog.V(2).InfoS("Uploaded report", "url", uploadURL, "checksum", checksum, "attempt", attempt)
+			return nil
+		}
+		if !retry {
+			return fmt.Errorf("failed to upload report to %s: %w", uploadURL, err)
+		}
+
+		klog.V(2).ErrorS(err, "failed to upload report, retry", "url", uploadURL,
+			"attempt", attempt, "backoff", backoff)
+		select {
+		case <-uploadCtx.Done():
+			return fmt.Errorf("failed to upload report to %s after %d attempts: %w", uploadURL, attempt, err)
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > uploadMaxBackoff {
+			backoff = uploadMaxBackoff
+		}
+	}
+}
+
+// postRunnerMetricReport sends report once. It returns true if the error is
+// retryable.
+func postRunnerMetricReport(ctx context.Context, uploadURL string, data []byte, checksum string) (retry bool, _ error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", uploadURL, bytes.NewReader(data))
+	if err != nil {
+		return false, fmt.Errorf("failed to init POST request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(types.RunnerReportChecksumHeader, checksum)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusCreated:
+		return false, nil
+	case http.StatusConflict:
+		klog.V(2).InfoS("Report has been uploaded, skip", "url", uploadURL)
+		return false, nil
+	}
+
+	// NOTE: Server returns 422 if checksum mismatches. The data might be
+	// corrupted in transit so that it's worth resending.
+	retry = resp.StatusCode == http.StatusUnprocessableEntity ||
+		resp.StatusCode >= http.StatusInternalServerError
+
+	err = fmt.Errorf("unexpected http code %v", resp.Status)
+	if errInRaw, rerr := io.ReadAll(resp.Body); rerr == nil {
+		herr := types.HTTPError{}
+		if json.Unmarshal(errInRaw, &herr) == nil {
+			err = fmt.Errorf("unexpected http code %v: %w", resp.Status, herr)
+		}
+	}
+	return retry, err
+}
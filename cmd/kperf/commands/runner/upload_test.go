@@ -0,0 +1,125 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package runner
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/kperf/api/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli"
+)
+
+func TestUploadRunnerMetricReportRetryOnChecksumMismatch(t *testing.T) {
+	var mu sync.Mutex
+	attempts := 0
+	var received []byte
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		attempts++
+		// NOTE: Corrupt the first upload.
+		if attempts == 1 {
+			data[0] ^= 0xff
+		}
+
+		sum := sha256.Sum256(data)
+		if hex.EncodeToString(sum[:]) != r.Header.Get(types.RunnerReportChecksumHeader) {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			_ = json.NewEncoder(w).Encode(types.HTTPError{ErrorMessage: "checksum mismatch"})
+			return
+		}
+		received = data
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	report := &types.RunnerMetricReport{Total: 10, Duration: "1s"}
+	err := uploadRunnerMetricReport(context.TODO(), srv.URL, report, 10*time.Second)
+	require.NoError(t, err)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	assert.Equal(t, 2, attempts)
+
+	got := &types.RunnerMetricReport{}
+	require.NoError(t, json.Unmarshal(received, got))
+	assert.Equal(t, report, got)
+}
+
+func TestUploadRunnerMetricReportNoRetryOnClientError(t *testing.T) {
+	var mu sync.Mutex
+	attempts := 0
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		attempts++
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(types.HTTPError{ErrorMessage: "no such runner"})
+	}))
+	defer srv.Close()
+
+	err := uploadRunnerMetricReport(context.TODO(), srv.URL, &types.RunnerMetricReport{}, 10*time.Second)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no such runner")
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 1, attempts)
+}
+
+func TestRunCommandUploadsFailureReportOnConfigLoadError(t *testing.T) {
+	var mu sync.Mutex
+	var received []*types.RunnerMetricReport
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := &types.RunnerMetricReport{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(report))
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		received = append(received, report)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	app := cli.NewApp()
+	app.Commands = []cli.Command{runCommand}
+
+	cfgPath := filepath.Join(t.TempDir(), "not-found.yaml")
+	err := app.Run([]string{"kperf", "run",
+		"--config=" + cfgPath,
+		"--upload-url=" + srv.URL,
+		"--upload-timeout=10s",
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to read file")
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	require.Len(t, received, 1)
+	assert.Equal(t, err.Error(), received[0].Error)
+}
@@ -11,6 +11,8 @@ import (
 	"time"
 
 	"github.com/Azure/kperf/api/types"
+	"github.com/Azure/kperf/cmd/kperf/commands/utils"
+	"github.com/Azure/kperf/metrics"
 	"github.com/Azure/kperf/runner"
 
 	"github.com/urfave/cli"
@@ -22,32 +24,108 @@ var resultCommand = cli.Command{
 	Flags: []cli.Flag{
 		cli.DurationFlag{
 			Name:  "timeout",
-			Usage: "Timeout for waiting result. Only valid when --wait",
+			Usage: "Timeout for waiting result. Only valid when --wait or --watch",
 			Value: time.Hour,
 		},
 		cli.BoolTFlag{
 			Name:  "wait",
 			Usage: "Wait until result is ready",
 		},
+		cli.StringFlag{
+			Name:  "slo",
+			Usage: "Path to the SLO file. Exit with non-zero code if the result violates SLO",
+		},
+		cli.BoolFlag{
+			Name:  "partial",
+			Usage: "Show the summary merged from the reports uploaded so far if runner groups are still running",
+		},
+		cli.BoolFlag{
+			Name:  "watch",
+			Usage: "Refresh partial summary every --watch-interval until result is ready",
+		},
+		cli.DurationFlag{
+			Name:  "watch-interval",
+			Usage: "The interval to refresh partial summary. Only valid when --watch",
+			Value: 30 * time.Second,
+		},
+		cli.StringFlag{
+			Name:  "runner",
+			Usage: "Print the raw report uploaded by the runner instead of summary",
+		},
+		cli.BoolFlag{
+			Name:  "all",
+			Usage: "Download all the runners' raw reports into --output-dir instead of summary",
+		},
+		cli.StringFlag{
+			Name:  "output-dir",
+			Usage: "The directory to store runners' raw reports as <runner group>/<runner>.json. Only valid when --all",
+		},
 	},
 	Action: func(cliCtx *cli.Context) error {
 		kubeCfgPath := cliCtx.GlobalString("kubeconfig")
 		wait := cliCtx.Bool("wait")
 
+		runnerName := cliCtx.String("runner")
+		all := cliCtx.Bool("all")
+		outputDir := cliCtx.String("output-dir")
+		switch {
+		case runnerName != "" && all:
+			return fmt.Errorf("--runner and --all are mutually exclusive")
+		case runnerName != "":
+			return runner.GetRunnerReport(context.Background(), kubeCfgPath, runnerName, os.Stdout)
+		case all:
+			if outputDir == "" {
+				return fmt.Errorf("required --output-dir with --all")
+			}
+			return runner.DownloadRunnerReports(context.Background(), kubeCfgPath, outputDir)
+		}
+
+		partial := cliCtx.Bool("partial")
+		watch := cliCtx.Bool("watch")
+		watchInterval := cliCtx.Duration("watch-interval")
+		if watch && watchInterval <= 0 {
+			return fmt.Errorf("invalid --watch-interval value: %v", watchInterval)
+		}
+
 		ctx := context.Background()
 		to := cliCtx.Duration("timeout")
-		if to > 0 && wait {
+		if to > 0 && (wait || watch) {
 			tctx, tcancel := context.WithTimeout(ctx, to)
 			defer tcancel()
 			ctx = tctx
 		}
 
-		res, err := runner.GetRunnerGroupResult(ctx, kubeCfgPath, wait)
+		var slo *types.SLO
+		if sloPath := cliCtx.String("slo"); sloPath != "" {
+			var err error
+			slo, err = utils.LoadSLOFromFile(sloPath)
+			if err != nil {
+				return err
+			}
+			if err := runner.ValidateRunnerGroupSLO(slo); err != nil {
+				return fmt.Errorf("invalid slo %s: %w", sloPath, err)
+			}
+		}
+
+		var res *types.RunnerGroupsReport
+		var err error
+		if watch {
+			res, err = watchRunnerGroupsResult(ctx, kubeCfgPath, watchInterval)
+		} else {
+			res, err = runner.GetRunnerGroupResult(ctx, kubeCfgPath, wait, partial)
+		}
 		if err != nil {
 			return err
 		}
 
-		return renderRunnerGroupsReport(res)
+		if slo != nil {
+			res.Verdict = metrics.EvaluateSLO(slo, &res.RunnerMetricReport)
+		}
+
+		if err := renderRunnerGroupsReport(res); err != nil {
+			return err
+		}
+		return utils.SLOViolationError(res.Verdict)
 	},
 }
 
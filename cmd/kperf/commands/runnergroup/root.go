@@ -27,5 +27,6 @@ var Command = cli.Command{
 		resultCommand,
 		serverCommand,
 		statusCommand,
+		stopCommand,
 	},
 }
@@ -21,10 +21,9 @@ var runCommand = cli.Command{
 	Name:  "run",
 	Usage: "run runner groups",
 	Flags: []cli.Flag{
-		// TODO(weifu): need https://github.com/Azure/kperf/issues/25 to support list
 		cli.StringSliceFlag{
 			Name:     "runnergroup",
-			Usage:    "The runner group spec's URI",
+			Usage:    "The runner group spec's URI. It can be specified multiple times to run runner groups concurrently",
 			Required: true,
 		},
 		cli.StringFlag{
@@ -46,6 +45,10 @@ var runCommand = cli.Command{
 			Name:  "affinity",
 			Usage: "Deploy server to the node with a specific labels (FORMAT: KEY=VALUE[,VALUE])",
 		},
+		cli.BoolFlag{
+			Name:  "apiserver-metrics",
+			Usage: "Show the change of kube-apiserver's metrics during runner groups in summary",
+		},
 	},
 	Action: func(cliCtx *cli.Context) error {
 		imgRef := cliCtx.String("runner-image")
@@ -67,17 +70,18 @@ var runCommand = cli.Command{
 		if err != nil {
 			return fmt.Errorf("failed to load runner group spec: %w", err)
 		}
-		if len(specs) != 1 {
-			return fmt.Errorf("only support one runner group right now. will support it after https://github.com/Azure/kperf/issues/25")
+		if err := types.ValidateRunnerGroupSpecs(specs); err != nil {
+			return fmt.Errorf("invalid runner group spec: %w", err)
 		}
 
 		kubeCfgPath := cliCtx.GlobalString("kubeconfig")
 		return runner.CreateRunnerGroupServer(context.Background(),
 			kubeCfgPath,
 			imgRef,
-			specs[0],
+			specs,
 			runner.WithRunCmdServerNodeSelectorsOpt(affinityLabels),
 			runner.WithRunCmdRunnerGroupFlowControl(priorityLevel, matchingPrecedence),
+			runner.WithRunCmdAPIServerMetricsOpt(cliCtx.Bool("apiserver-metrics")),
 		)
 	},
 }
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/Azure/kperf/request"
 	"github.com/Azure/kperf/runner"
 	runnergroup "github.com/Azure/kperf/runner/group"
 
@@ -52,6 +53,14 @@ var serverCommand = cli.Command{
 			Usage:    "The runner result should be stored in that path",
 			Required: true,
 		},
+		cli.StringFlag{
+			Name:  "runner-flowcontrol",
+			Usage: "The flowcontrol applied to runners, only shown in summary (FORMAT: PriorityLevel:MatchingPrecedence)",
+		},
+		cli.BoolFlag{
+			Name:  "apiserver-metrics",
+			Usage: "Scrape /metrics from each kube-apiserver replica before and after runner groups and show the change in summary",
+		},
 	},
 	Hidden: true,
 	Action: func(cliCtx *cli.Context) error {
@@ -72,7 +81,23 @@ var serverCommand = cli.Command{
 		dataDir := cliCtx.String("data")
 		addrs := cliCtx.StringSlice("address")
 
-		srv, err := runner.NewServer(dataDir, addrs, groupHandlers...)
+		opts := []runner.ServerOpt{}
+		if v := cliCtx.String("runner-flowcontrol"); v != "" {
+			priorityLevel, matchingPrecedence, err := parseFlowControl(v)
+			if err != nil {
+				return fmt.Errorf("failed to parse runner-flowcontrol: %w", err)
+			}
+			opts = append(opts, runner.WithServerFlowControlOpt(priorityLevel, matchingPrecedence))
+		}
+		if cliCtx.Bool("apiserver-metrics") {
+			scraper, err := request.NewAPIServerMetricsScraper(cliCtx.GlobalString("kubeconfig"), name)
+			if err != nil {
+				return fmt.Errorf("failed to create kube-apiserver's metrics scraper: %w", err)
+			}
+			opts = append(opts, runner.WithServerAPIServerMetricsScraperOpt(scraper))
+		}
+
+		srv, err := runner.NewServer(dataDir, addrs, groupHandlers, opts...)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,25 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package runnergroup
+
+import (
+	"context"
+
+	"github.com/Azure/kperf/runner"
+
+	"github.com/urfave/cli"
+)
+
+var stopCommand = cli.Command{
+	Name:      "stop",
+	Usage:     "abort running runner groups and keep partial result",
+	ArgsUsage: "[NAME...]",
+	Description: "The runners upload partial reports before exit. " +
+		"All the runner groups are aborted if no name is specified.",
+	Action: func(cliCtx *cli.Context) error {
+		kubeCfgPath := cliCtx.GlobalString("kubeconfig")
+
+		return runner.StopRunnerGroups(context.Background(), kubeCfgPath, cliCtx.Args())
+	},
+}
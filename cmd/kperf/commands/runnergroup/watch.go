@@ -0,0 +1,109 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package runnergroup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Azure/kperf/api/types"
+	"github.com/Azure/kperf/runner"
+)
+
+// watchRunnerGroupsResult renders partial summary into stderr every interval
+// and returns the final summary after all the runner groups finish.
+func watchRunnerGroupsResult(ctx context.Context, kubeCfgPath string, interval time.Duration) (*types.RunnerGroupsReport, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		res, err := runner.GetRunnerGroupResult(ctx, kubeCfgPath, false, true)
+		if err != nil {
+			return nil, err
+		}
+		if !res.Partial {
+			return res, nil
+		}
+
+		if err := renderPartialSummary(os.Stderr, res); err != nil {
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// renderPartialSummary renders partial summary into table format, for
+// instance,
+//
+//	partial summary at 2024-10-29T00:30:03Z: 12 runners included
+//	NAME                   STATE     RUNNERS   TOTAL   ERRORS   P99
+//	runnergroup-server-0   running   12/20     1200    3        0.0450s
+func renderPartialSummary(w io.Writer, res *types.RunnerGroupsReport) error {
+	fmt.Fprintf(w, "partial summary at %s: %d runners included\n",
+		time.Now().UTC().Format(time.RFC3339), len(res.IncludedRunners))
+
+	tw := tabwriter.NewWriter(w, 1, 12, 3, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tSTATE\tRUNNERS\tTOTAL\tERRORS\tP99\t")
+	for _, g := range res.Groups {
+		state := types.RunnerGroupStatusStateUnknown
+		if g.Status != nil {
+			state = g.Status.State
+		}
+
+		var count int32
+		if g.Spec != nil {
+			count = g.Spec.Count
+		}
+
+		included := 0
+		for _, r := range g.Runners {
+			if r.HasReport || r.Interim {
+				included++
+			}
+		}
+
+		report := g.Report
+		if report == nil {
+			report = &types.RunnerMetricReport{}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d/%d\t%d\t%d\t%s\t\n",
+			g.Name,
+			state,
+			included, count,
+			report.Total,
+			countErrors(report),
+			formatPercentileLatency(report, 0.99),
+		)
+	}
+	return tw.Flush()
+}
+
+// countErrors returns the total number of errors in report.
+func countErrors(report *types.RunnerMetricReport) int64 {
+	total := int64(0)
+	for _, ec := range report.ErrorCounts {
+		total += ec.Count
+	}
+	return total
+}
+
+// formatPercentileLatency returns the latency at percentile, or "-" if there
+// is no such percentile.
+func formatPercentileLatency(report *types.RunnerMetricReport, percentile float64) string {
+	for _, pl := range report.PercentileLatencies {
+		if pl[0] == percentile {
+			return fmt.Sprintf("%.4fs", pl[1])
+		}
+	}
+	return "-"
+}
@@ -10,6 +10,9 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/Azure/kperf/api/types"
+
+	"gopkg.in/yaml.v2"
 	flowcontrolv1 "k8s.io/api/flowcontrol/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -56,6 +59,46 @@ func KeyValueMap(strs []string) (map[string]string, error) {
 	return res, nil
 }
 
+// LoadSLOFromFile loads and validates SLO from YAML file.
+func LoadSLOFromFile(sloPath string) (*types.SLO, error) {
+	raw, err := os.ReadFile(sloPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", sloPath, err)
+	}
+
+	var slo types.SLO
+	if err := yaml.Unmarshal(raw, &slo); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s from yaml format: %w", sloPath, err)
+	}
+
+	if err := slo.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid slo %s: %w", sloPath, err)
+	}
+	return &slo, nil
+}
+
+// SLOViolationError returns error which lists all the failed checks. It
+// returns nil if verdict passed.
+func SLOViolationError(verdict *types.SLOVerdict) error {
+	if verdict == nil || verdict.Passed {
+		return nil
+	}
+
+	violations := make([]string, 0, len(verdict.Checks))
+	for _, check := range verdict.Checks {
+		if check.Passed {
+			continue
+		}
+
+		msg := fmt.Sprintf("%s (threshold: %v, actual: %v)", check.Name, check.Threshold, check.Actual)
+		if check.Message != "" {
+			msg = fmt.Sprintf("%s (threshold: %v, %s)", check.Name, check.Threshold, check.Message)
+		}
+		violations = append(violations, msg)
+	}
+	return fmt.Errorf("SLO violated: %s", strings.Join(violations, ", "))
+}
+
 // inCluster is to check if current process is in pod.
 func inCluster() bool {
 	f, err := os.Stat("/var/run/secrets/kubernetes.io/serviceaccount/token")
@@ -57,7 +57,11 @@ var benchCiliumCustomResourceListCase = cli.Command{
 		commonFlags...,
 	),
 	Action: func(cliCtx *cli.Context) error {
-		_, err := renderBenchmarkReportInterceptor(ciliumCustomResourceListRun)(cliCtx)
+		_, err := failOnSLOViolationInterceptor(
+			renderBenchmarkReportInterceptor(
+				addSLOVerdictInterceptor(ciliumCustomResourceListRun),
+			),
+		)(cliCtx)
 		return err
 	},
 }
@@ -33,8 +33,12 @@ that nodes. It repeats to create and delete job. The load profile is fixed.
 		commonFlags...,
 	),
 	Action: func(cliCtx *cli.Context) error {
-		_, err := renderBenchmarkReportInterceptor(
-			addAPIServerCoresInfoInterceptor(benchNode100Job1Pod3KCaseRun),
+		_, err := failOnSLOViolationInterceptor(
+			renderBenchmarkReportInterceptor(
+				addSLOVerdictInterceptor(
+					addAPIServerCoresInfoInterceptor(benchNode100Job1Pod3KCaseRun),
+				),
+			),
 		)(cliCtx)
 		return err
 	},
@@ -52,8 +52,12 @@ benchmark.
 		commonFlags...,
 	),
 	Action: func(cliCtx *cli.Context) error {
-		_, err := renderBenchmarkReportInterceptor(
-			addAPIServerCoresInfoInterceptor(benchNode100DeploymentNPod10KRun),
+		_, err := failOnSLOViolationInterceptor(
+			renderBenchmarkReportInterceptor(
+				addSLOVerdictInterceptor(
+					addAPIServerCoresInfoInterceptor(benchNode100DeploymentNPod10KRun),
+				),
+			),
 		)(cliCtx)
 		return err
 	},
@@ -53,6 +53,10 @@ var Command = cli.Command{
 			Name:  "result",
 			Usage: "Path to the file which stores results",
 		},
+		cli.StringFlag{
+			Name:  "slo",
+			Usage: "Path to the SLO file. It can override SLO defined by load profile. Exit with non-zero code if the result violates SLO",
+		},
 	},
 	Subcommands: []cli.Command{
 		benchNode10Job1Pod100Case,
@@ -15,6 +15,8 @@ import (
 	internaltypes "github.com/Azure/kperf/contrib/internal/types"
 	"github.com/Azure/kperf/contrib/log"
 	"github.com/Azure/kperf/contrib/utils"
+	"github.com/Azure/kperf/metrics"
+	"github.com/Azure/kperf/runner"
 
 	"github.com/urfave/cli"
 	"gopkg.in/yaml.v2"
@@ -59,6 +61,56 @@ func addAPIServerCoresInfoInterceptor(handler subcmdActionFunc) subcmdActionFunc
 	}
 }
 
+// addSLOVerdictInterceptor evaluates SLO against runner groups' report. The
+// SLO is loaded from --slo or the load profile.
+func addSLOVerdictInterceptor(handler subcmdActionFunc) subcmdActionFunc {
+	return func(cliCtx *cli.Context) (*internaltypes.BenchmarkReport, error) {
+		var slo *types.SLO
+		if sloPath := cliCtx.GlobalString("slo"); sloPath != "" {
+			var err error
+			slo, err = kperfcmdutils.LoadSLOFromFile(sloPath)
+			if err != nil {
+				return nil, err
+			}
+			if err := runner.ValidateRunnerGroupSLO(slo); err != nil {
+				return nil, fmt.Errorf("invalid slo %s: %w", sloPath, err)
+			}
+		}
+
+		report, err := handler(cliCtx)
+		if err != nil {
+			return nil, err
+		}
+
+		if slo == nil && report.LoadSpec.Profile != nil {
+			slo = report.LoadSpec.Profile.SLO
+			if slo != nil {
+				if err := runner.ValidateRunnerGroupSLO(slo); err != nil {
+					return nil, fmt.Errorf("invalid slo in load profile: %w", err)
+				}
+			}
+		}
+
+		if slo != nil {
+			report.Result.Verdict = metrics.EvaluateSLO(slo, &report.Result.RunnerMetricReport)
+		}
+		return report, nil
+	}
+}
+
+// failOnSLOViolationInterceptor returns error if benchmark report violates
+// SLO. It should be the outermost interceptor so that the report has been
+// rendered.
+func failOnSLOViolationInterceptor(handler subcmdActionFunc) subcmdActionFunc {
+	return func(cliCtx *cli.Context) (*internaltypes.BenchmarkReport, error) {
+		report, err := handler(cliCtx)
+		if err != nil {
+			return nil, err
+		}
+		return report, kperfcmdutils.SLOViolationError(report.Result.Verdict)
+	}
+}
+
 // renderBenchmarkReportInterceptor renders benchmark report into file or stdout.
 func renderBenchmarkReportInterceptor(handler subcmdActionFunc) subcmdActionFunc {
 	return func(cliCtx *cli.Context) (*internaltypes.BenchmarkReport, error) {
@@ -0,0 +1,207 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/kperf/api/types"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// apiserverMetricPrefixes is the list of kube-apiserver's metrics to be
+// compared. The item ending with underscore matches metrics by prefix.
+var apiserverMetricPrefixes = []string{
+	"apiserver_request_duration_seconds",
+	"apiserver_flowcontrol_",
+	"apiserver_storage_objects",
+	"etcd_request_duration_seconds",
+	"process_cpu_seconds_total",
+	"process_resident_memory_bytes",
+}
+
+// histogramDeltaPercentiles is the list of percentiles estimated from
+// histogram's buckets.
+var histogramDeltaPercentiles = []float64{0.5, 0.9, 0.99}
+
+// APIServerMetricsSnapshot is the parsed kube-apiserver's metrics at one
+// moment.
+type APIServerMetricsSnapshot struct {
+	// Timestamp is when the metrics were scraped.
+	Timestamp time.Time
+	families  map[string]*dto.MetricFamily
+}
+
+// ParseAPIServerMetrics parses Prometheus text format and only keeps the
+// interesting metrics.
+func ParseAPIServerMetrics(r io.Reader, now time.Time) (*APIServerMetricsSnapshot, error) {
+	var parser expfmt.TextParser
+
+	families, err := parser.TextToMetricFamilies(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse metrics: %w", err)
+	}
+
+	for name := range families {
+		if !isAPIServerMetricWanted(name) {
+			delete(families, name)
+		}
+	}
+	return &APIServerMetricsSnapshot{Timestamp: now, families: families}, nil
+}
+
+// isAPIServerMetricWanted returns true if the metric should be compared.
+func isAPIServerMetricWanted(name string) bool {
+	for _, prefix := range apiserverMetricPrefixes {
+		if strings.HasSuffix(prefix, "_") {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+			continue
+		}
+		if name == prefix {
+			return true
+		}
+	}
+	return false
+}
+
+// DiffAPIServerMetrics returns the change between two snapshots. The
+// counters and histograms without increase are ignored.
+func DiffAPIServerMetrics(before, after *APIServerMetricsSnapshot) *types.APIServerMetricsDelta {
+	res := &types.APIServerMetricsDelta{
+		Duration:   after.Timestamp.Sub(before.Timestamp).String(),
+		Counters:   map[string]float64{},
+		Gauges:     map[string][2]float64{},
+		Histograms: map[string]*types.HistogramDelta{},
+	}
+
+	for name, family := range after.families {
+		old := map[string]*dto.Metric{}
+		if f, ok := before.families[name]; ok {
+			for _, m := range f.GetMetric() {
+				old[seriesKey(name, m)] = m
+			}
+		}
+
+		for _, m := range family.GetMetric() {
+			key := seriesKey(name, m)
+			prev := old[key]
+
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				if d := counterIncrease(prev.GetCounter().GetValue(), m.GetCounter().GetValue()); d > 0 {
+					res.Counters[key] = d
+				}
+			case dto.MetricType_GAUGE:
+				v := [2]float64{prev.GetGauge().GetValue(), m.GetGauge().GetValue()}
+				if v[0] != 0 || v[1] != 0 {
+					res.Gauges[key] = v
+				}
+			case dto.MetricType_HISTOGRAM:
+				if d := histogramIncrease(prev.GetHistogram(), m.GetHistogram()); d != nil {
+					res.Histograms[key] = d
+				}
+			}
+		}
+	}
+	return res
+}
+
+// counterIncrease returns the increase of counter. If the counter has been
+// reset, for instance, kube-apiserver restarted, it returns current value.
+func counterIncrease(before, after float64) float64 {
+	if after < before {
+		return after
+	}
+	return after - before
+}
+
+// histogramIncrease returns the increase of histogram. It returns nil if
+// there is no new observation.
+func histogramIncrease(before, after *dto.Histogram) *types.HistogramDelta {
+	if after.GetSampleCount() < before.GetSampleCount() {
+		// reset
+		before = nil
+	}
+
+	count := after.GetSampleCount() - before.GetSampleCount()
+	if count == 0 {
+		return nil
+	}
+
+	oldBuckets := map[float64]uint64{}
+	for _, b := range before.GetBucket() {
+		oldBuckets[b.GetUpperBound()] = b.GetCumulativeCount()
+	}
+
+	buckets := make([]cumulativeBucket, 0, len(after.GetBucket()))
+	for _, b := range after.GetBucket() {
+		buckets = append(buckets, cumulativeBucket{
+			upperBound: b.GetUpperBound(),
+			count:      b.GetCumulativeCount() - oldBuckets[b.GetUpperBound()],
+		})
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		return buckets[i].upperBound < buckets[j].upperBound
+	})
+
+	res := &types.HistogramDelta{
+		Count: count,
+		Sum:   after.GetSampleSum() - before.GetSampleSum(),
+	}
+	for _, p := range histogramDeltaPercentiles {
+		res.Percentiles = append(res.Percentiles, [2]float64{p, bucketQuantile(p, count, buckets)})
+	}
+	return res
+}
+
+// cumulativeBucket is Prometheus histogram's bucket.
+type cumulativeBucket struct {
+	upperBound float64
+	count      uint64
+}
+
+// bucketQuantile estimates quantile by linear interpolation within the
+// bucket, like Prometheus's histogram_quantile. The buckets should be sorted
+// by upper bound.
+func bucketQuantile(p float64, total uint64, buckets []cumulativeBucket) float64 {
+	rank := p * float64(total)
+
+	lowerBound, lowerCount := 0.0, uint64(0)
+	for _, b := range buckets {
+		if float64(b.count) >= rank {
+			if math.IsInf(b.upperBound, 1) || b.count == lowerCount {
+				return lowerBound
+			}
+			return lowerBound + (b.upperBound-lowerBound)*
+				(rank-float64(lowerCount))/float64(b.count-lowerCount)
+		}
+		lowerBound, lowerCount = b.upperBound, b.count
+	}
+	// NOTE: The rest of observations are in implicit +Inf bucket.
+	return lowerBound
+}
+
+// seriesKey returns metric name with sorted labels.
+func seriesKey(name string, m *dto.Metric) string {
+	labels := m.GetLabel()
+	if len(labels) == 0 {
+		return name
+	}
+
+	pairs := make([]string, 0, len(labels))
+	for _, l := range labels {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", l.GetName(), l.GetValue()))
+	}
+	sort.Strings(pairs)
+	return name + "{" + strings.Join(pairs, ",") + "}"
+}
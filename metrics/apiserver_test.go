@@ -0,0 +1,85 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffAPIServerMetrics(t *testing.T) {
+	before := `
+# TYPE apiserver_flowcontrol_rejected_requests_total counter
+apiserver_flowcontrol_rejected_requests_total{priority_level="workload-low",reason="queue-full"} 10
+# TYPE apiserver_storage_objects gauge
+apiserver_storage_objects{resource="pods"} 100
+apiserver_storage_objects{resource="nodes"} 10
+# TYPE apiserver_request_duration_seconds histogram
+apiserver_request_duration_seconds_bucket{resource="pods",verb="LIST",le="0.1"} 10
+apiserver_request_duration_seconds_bucket{resource="pods",verb="LIST",le="1"} 10
+apiserver_request_duration_seconds_bucket{resource="pods",verb="LIST",le="+Inf"} 10
+apiserver_request_duration_seconds_sum{resource="pods",verb="LIST"} 0.5
+apiserver_request_duration_seconds_count{resource="pods",verb="LIST"} 10
+# TYPE process_cpu_seconds_total counter
+process_cpu_seconds_total 100
+# TYPE go_goroutines gauge
+go_goroutines 100
+`
+	after := `
+# TYPE apiserver_flowcontrol_rejected_requests_total counter
+apiserver_flowcontrol_rejected_requests_total{priority_level="workload-low",reason="queue-full"} 30
+apiserver_flowcontrol_rejected_requests_total{priority_level="global-default",reason="timeout"} 0
+# TYPE apiserver_storage_objects gauge
+apiserver_storage_objects{resource="pods"} 200
+apiserver_storage_objects{resource="nodes"} 10
+# TYPE apiserver_request_duration_seconds histogram
+apiserver_request_duration_seconds_bucket{resource="pods",verb="LIST",le="0.1"} 20
+apiserver_request_duration_seconds_bucket{resource="pods",verb="LIST",le="1"} 110
+apiserver_request_duration_seconds_bucket{resource="pods",verb="LIST",le="+Inf"} 110
+apiserver_request_duration_seconds_sum{resource="pods",verb="LIST"} 50.5
+apiserver_request_duration_seconds_count{resource="pods",verb="LIST"} 110
+# TYPE process_cpu_seconds_total counter
+process_cpu_seconds_total 160
+# TYPE go_goroutines gauge
+go_goroutines 200
+`
+	now := time.Now()
+	b, err := ParseAPIServerMetrics(strings.NewReader(before), now)
+	require.NoError(t, err)
+	a, err := ParseAPIServerMetrics(strings.NewReader(after), now.Add(time.Minute))
+	require.NoError(t, err)
+
+	delta := DiffAPIServerMetrics(b, a)
+	assert.Equal(t, "1m0s", delta.Duration)
+	assert.Equal(t, map[string]float64{
+		`apiserver_flowcontrol_rejected_requests_total{priority_level="workload-low",reason="queue-full"}`: 20,
+		`process_cpu_seconds_total`: 60,
+	}, delta.Counters)
+	assert.Equal(t, map[string][2]float64{
+		`apiserver_storage_objects{resource="pods"}`:  {100, 200},
+		`apiserver_storage_objects{resource="nodes"}`: {10, 10},
+	}, delta.Gauges)
+
+	require.Len(t, delta.Histograms, 1)
+	h := delta.Histograms[`apiserver_request_duration_seconds{resource="pods",verb="LIST"}`]
+	require.NotNil(t, h)
+	assert.Equal(t, uint64(100), h.Count)
+	assert.InDelta(t, 50, h.Sum, 1e-9)
+	assert.Equal(t, [2]float64{0.5, 0.5}, h.Percentiles[0])
+}
+
+func TestBucketQuantile(t *testing.T) {
+	buckets := []cumulativeBucket{
+		{upperBound: 0.1, count: 50},
+		{upperBound: 1, count: 90},
+	}
+	assert.InDelta(t, 0.1, bucketQuantile(0.5, 100, buckets), 1e-9)
+	assert.InDelta(t, 1, bucketQuantile(0.9, 100, buckets), 1e-9)
+	// the rest are in +Inf bucket
+	assert.Equal(t, float64(1), bucketQuantile(0.99, 100, buckets))
+}
@@ -0,0 +1,169 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package metrics
+
+import (
+	"math"
+	"math/rand/v2"
+	"sort"
+
+	"github.com/Azure/kperf/api/types"
+)
+
+// DefaultErrorSamples is the default number of sampled error examples.
+const DefaultErrorSamples = 100
+
+// errorReservoir keeps a uniform random sample of observed errors with
+// bounded size.
+//
+// NOTE: It's not thread-safe.
+type errorReservoir struct {
+	size    int
+	seen    int64
+	samples []types.ResponseError
+}
+
+// newErrorReservoir returns errorReservoir which keeps at most size errors.
+func newErrorReservoir(size int) *errorReservoir {
+	return &errorReservoir{size: size}
+}
+
+// add observes the error.
+func (r *errorReservoir) add(err types.ResponseError) {
+	if r.size <= 0 {
+		return
+	}
+
+	r.seen++
+	if len(r.samples) < r.size {
+		r.samples = append(r.samples, err)
+		return
+	}
+
+	//nolint:gosec // no need for cryptographic randomness
+	if idx := rand.Int64N(r.seen); idx < int64(r.size) {
+		r.samples[idx] = err
+	}
+}
+
+// dump returns the copy of sampled errors.
+func (r *errorReservoir) dump() []types.ResponseError {
+	res := make([]types.ResponseError, len(r.samples))
+	copy(res, r.samples)
+	return res
+}
+
+// SampleErrors returns at most size errors sampled from errs.
+func SampleErrors(size int, errs ...[]types.ResponseError) []types.ResponseError {
+	r := newErrorReservoir(size)
+	for _, es := range errs {
+		for _, e := range es {
+			r.add(e)
+		}
+	}
+	return r.dump()
+}
+
+// WeightedErrors is errors sampled from Seen errors.
+type WeightedErrors struct {
+	Samples []types.ResponseError
+	// Seen is the number of errors observed by the sampler.
+	Seen int64
+}
+
+// MergeErrorSamples returns at most size errors sampled from weighted
+// errors. Each sample stands for Seen/len(Samples) errors so that the source
+// with more errors contributes more samples.
+func MergeErrorSamples(size int, errs ...WeightedErrors) []types.ResponseError {
+	if size <= 0 {
+		return []types.ResponseError{}
+	}
+
+	type keyedError struct {
+		key float64
+		err types.ResponseError
+	}
+
+	// NOTE: It's weighted random sampling without replacement (A-Res)
+	// which keeps the errors with the largest u^(1/weight).
+	keyed := []keyedError{}
+	for _, we := range errs {
+		if len(we.Samples) == 0 {
+			continue
+		}
+
+		seen := we.Seen
+		if seen < int64(len(we.Samples)) {
+			seen = int64(len(we.Samples))
+		}
+		weight := float64(seen) / float64(len(we.Samples))
+
+		for _, e := range we.Samples {
+			//nolint:gosec // no need for cryptographic randomness
+			keyed = append(keyed, keyedError{key: math.Pow(rand.Float64(), 1/weight), err: e})
+		}
+	}
+
+	sort.Slice(keyed, func(i, j int) bool {
+		return keyed[i].key > keyed[j].key
+	})
+	if len(keyed) > size {
+		keyed = keyed[:size]
+	}
+
+	res := make([]types.ResponseError, 0, len(keyed))
+	for _, ke := range keyed {
+		res = append(res, ke.err)
+	}
+	return res
+}
+
+// errorCountKey is the key of types.ErrorCount.
+type errorCountKey struct {
+	typ  types.ResponseErrorType
+	code int
+	tag  string
+}
+
+// MergeErrorCounts merges counts with the same type, code and tag. The result
+// is sorted by count in descending order.
+func MergeErrorCounts(counts ...[]types.ErrorCount) []types.ErrorCount {
+	merged := map[errorCountKey]int64{}
+	for _, cs := range counts {
+		for _, c := range cs {
+			merged[errorCountKey{typ: c.Type, code: c.Code, tag: c.Tag}] += c.Count
+		}
+	}
+	return dumpErrorCounts(merged)
+}
+
+// dumpErrorCounts converts counts into sorted types.ErrorCount.
+func dumpErrorCounts(counts map[errorCountKey]int64) []types.ErrorCount {
+	if len(counts) == 0 {
+		return nil
+	}
+
+	res := make([]types.ErrorCount, 0, len(counts))
+	for k, n := range counts {
+		res = append(res, types.ErrorCount{
+			Type:  k.typ,
+			Code:  k.code,
+			Tag:   k.tag,
+			Count: n,
+		})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Count != res[j].Count {
+			return res[i].Count > res[j].Count
+		}
+		if res[i].Type != res[j].Type {
+			return res[i].Type < res[j].Type
+		}
+		if res[i].Code != res[j].Code {
+			return res[i].Code < res[j].Code
+		}
+		return res[i].Tag < res[j].Tag
+	})
+	return res
+}
@@ -0,0 +1,43 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package metrics
+
+import (
+	"testing"
+
+	"github.com/Azure/kperf/api/types"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeErrorSamples(t *testing.T) {
+	samplesOf := func(url string, n int) []types.ResponseError {
+		res := make([]types.ResponseError, 0, n)
+		for i := 0; i < n; i++ {
+			res = append(res, types.ResponseError{URL: url})
+		}
+		return res
+	}
+
+	merged := MergeErrorSamples(100,
+		WeightedErrors{Samples: samplesOf("/busy", 100), Seen: 1000000},
+		WeightedErrors{Samples: samplesOf("/quiet", 10), Seen: 10},
+	)
+	assert.Len(t, merged, 100)
+
+	busy := 0
+	for _, e := range merged {
+		if e.URL == "/busy" {
+			busy++
+		}
+	}
+	assert.GreaterOrEqual(t, busy, 95)
+
+	// Equal weights keep all the samples if there is room.
+	assert.Len(t, MergeErrorSamples(100,
+		WeightedErrors{Samples: samplesOf("/a", 10), Seen: 10},
+		WeightedErrors{Samples: samplesOf("/b", 10)},
+	), 20)
+	assert.Empty(t, MergeErrorSamples(0, WeightedErrors{Samples: samplesOf("/a", 10)}))
+}
@@ -0,0 +1,247 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package metrics
+
+import (
+	"math"
+	"sort"
+
+	"github.com/Azure/kperf/api/types"
+)
+
+const (
+	// defaultRelativeAccuracy is the maximum relative error of percentile
+	// calculated by Histogram.
+	defaultRelativeAccuracy = 0.01
+
+	// minIndexableLatency is the smallest latency in seconds which can be
+	// indexed. The smaller latencies are counted into zero bucket.
+	minIndexableLatency = 1e-9
+)
+
+// Histogram is a mergeable latency histogram with logarithmic buckets. The
+// memory usage depends on the range of latencies instead of the number of
+// latencies.
+//
+// NOTE: It's not thread-safe.
+type Histogram struct {
+	relativeAccuracy float64
+	gamma            float64
+	logGamma         float64
+
+	count     int64
+	sum       float64
+	min       float64
+	max       float64
+	zeroCount int64
+	buckets   map[int]int64
+}
+
+// NewHistogram returns empty histogram.
+func NewHistogram() *Histogram {
+	gamma := (1 + defaultRelativeAccuracy) / (1 - defaultRelativeAccuracy)
+	return &Histogram{
+		relativeAccuracy: defaultRelativeAccuracy,
+		gamma:            gamma,
+		logGamma:         math.Log(gamma),
+		buckets:          map[int]int64{},
+	}
+}
+
+// MergeHistograms returns new histogram merged from serialized histograms.
+func MergeHistograms(hs ...*types.LatencyHistogram) *Histogram {
+	h := NewHistogram()
+	for _, s := range hs {
+		h.Merge(s)
+	}
+	return h
+}
+
+// Observe records one latency in seconds.
+func (h *Histogram) Observe(seconds float64) {
+	h.observeN(seconds, 1)
+}
+
+func (h *Histogram) observeN(seconds float64, n int64) {
+	if n <= 0 {
+		return
+	}
+
+	if h.count == 0 || seconds < h.min {
+		h.min = seconds
+	}
+	if h.count == 0 || seconds > h.max {
+		h.max = seconds
+	}
+	h.count += n
+	h.sum += seconds * float64(n)
+
+	if seconds < minIndexableLatency {
+		h.zeroCount += n
+		return
+	}
+	h.buckets[h.index(seconds)] += n
+}
+
+// Merge merges serialized histogram into h. If the relative accuracy is
+// different, the latencies are re-bucketed by the value of source bucket.
+func (h *Histogram) Merge(s *types.LatencyHistogram) {
+	if s == nil || s.Count == 0 {
+		return
+	}
+
+	if s.RelativeAccuracy == h.relativeAccuracy {
+		if h.count == 0 || s.Min < h.min {
+			h.min = s.Min
+		}
+		if h.count == 0 || s.Max > h.max {
+			h.max = s.Max
+		}
+		h.count += s.Count
+		h.sum += s.Sum
+		h.zeroCount += s.ZeroCount
+		for _, b := range s.Buckets {
+			h.buckets[int(b[0])] += b[1]
+		}
+		return
+	}
+
+	// NOTE: Keep min, max and sum exact even if the buckets aren't.
+	lo, hi, sum := s.Min, s.Max, h.sum+s.Sum
+	if h.count > 0 {
+		lo, hi = math.Min(lo, h.min), math.Max(hi, h.max)
+	}
+
+	h.observeN(0, s.ZeroCount)
+	if s.RelativeAccuracy <= 0 || s.RelativeAccuracy >= 1 {
+		// Unknown bucket layout. Use max latency to be conservative.
+		h.observeN(s.Max, s.Count-s.ZeroCount)
+	} else {
+		src := &Histogram{gamma: (1 + s.RelativeAccuracy) / (1 - s.RelativeAccuracy)}
+		for _, b := range s.Buckets {
+			h.observeN(src.value(int(b[0])), b[1])
+		}
+	}
+	h.min, h.max, h.sum = lo, hi, sum
+}
+
+// Count returns the number of observed latencies.
+func (h *Histogram) Count() int64 {
+	return h.count
+}
+
+// Quantile returns the latency at quantile p in [0, 1].
+func (h *Histogram) Quantile(p float64) float64 {
+	return h.Quantiles(p)[0]
+}
+
+// Quantiles returns the latencies at quantiles ps in [0, 1]. It sorts
+// buckets once and walks cumulative counts for all the quantiles.
+func (h *Histogram) Quantiles(ps ...float64) []float64 {
+	res := make([]float64, len(ps))
+	if h.count == 0 {
+		return res
+	}
+
+	// NOTE: Walk quantiles in ascending order so that buckets are walked
+	// once. The result keeps the order of ps.
+	order := make([]int, len(ps))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return ps[order[i]] < ps[order[j]]
+	})
+
+	indexes := make([]int, 0, len(h.buckets))
+	for idx := range h.buckets {
+		indexes = append(indexes, idx)
+	}
+	sort.Ints(indexes)
+
+	seen, bi := h.zeroCount, 0
+	for _, i := range order {
+		p := ps[i]
+		switch {
+		case p <= 0:
+			res[i] = h.min
+			continue
+		case p >= 1:
+			res[i] = h.max
+			continue
+		}
+
+		// NOTE: It's aligned with BuildPercentileLatencies.
+		rank := int64(math.Ceil(float64(h.count) * p))
+		if rank < 1 {
+			rank = 1
+		}
+		if rank <= h.zeroCount {
+			res[i] = h.min
+			continue
+		}
+
+		for bi < len(indexes) && seen < rank {
+			seen += h.buckets[indexes[bi]]
+			bi++
+		}
+		if seen < rank {
+			res[i] = h.max
+			continue
+		}
+		res[i] = math.Min(math.Max(h.value(indexes[bi-1]), h.min), h.max)
+	}
+	return res
+}
+
+// PercentileLatencies returns percentile latencies in the same format as
+// BuildPercentileLatencies.
+func (h *Histogram) PercentileLatencies() [][2]float64 {
+	if h.count == 0 {
+		return nil
+	}
+
+	values := h.Quantiles(percentiles...)
+	res := make([][2]float64, len(percentiles))
+	for pi, pv := range percentiles {
+		res[pi] = [2]float64{pv, values[pi]}
+	}
+	return res
+}
+
+// Snapshot returns serialized histogram.
+func (h *Histogram) Snapshot() *types.LatencyHistogram {
+	s := &types.LatencyHistogram{
+		RelativeAccuracy: h.relativeAccuracy,
+		Count:            h.count,
+		Sum:              h.sum,
+		Min:              h.min,
+		Max:              h.max,
+		ZeroCount:        h.zeroCount,
+	}
+
+	if len(h.buckets) == 0 {
+		return s
+	}
+
+	s.Buckets = make([][2]int64, 0, len(h.buckets))
+	for idx, n := range h.buckets {
+		s.Buckets = append(s.Buckets, [2]int64{int64(idx), n})
+	}
+	sort.Slice(s.Buckets, func(i, j int) bool {
+		return s.Buckets[i][0] < s.Buckets[j][0]
+	})
+	return s
+}
+
+// index returns the index of bucket (gamma^(index-1), gamma^index].
+func (h *Histogram) index(seconds float64) int {
+	return int(math.Ceil(math.Log(seconds) / h.logGamma))
+}
+
+// value returns the value of bucket whose relative error to any latency in
+// that bucket is less than relative accuracy.
+func (h *Histogram) value(index int) float64 {
+	return 2 * math.Pow(h.gamma, float64(index)) / (h.gamma + 1)
+}
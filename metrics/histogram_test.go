@@ -0,0 +1,104 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package metrics
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/Azure/kperf/api/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistogramPercentileLatencies(t *testing.T) {
+	ls := make([]float64, 0, 10000)
+	h := NewHistogram()
+	for i := 1; i <= 10000; i++ {
+		l := float64(i) / 1000
+		ls = append(ls, l)
+		h.Observe(l)
+	}
+
+	assert.Equal(t, int64(10000), h.Count())
+
+	expected := BuildPercentileLatencies(ls)
+	got := h.PercentileLatencies()
+	require.Len(t, got, len(expected))
+	for i := range expected {
+		assert.Equal(t, expected[i][0], got[i][0])
+		assert.InEpsilon(t, expected[i][1], got[i][1], defaultRelativeAccuracy)
+	}
+
+	// min and max are exact
+	assert.Equal(t, 0.001, got[0][1])
+	assert.Equal(t, float64(10), got[len(got)-1][1])
+
+	assert.Nil(t, NewHistogram().PercentileLatencies())
+
+	// quantiles in any order
+	ps := []float64{0.99, 0, 0.5, 1, 0.5}
+	values := h.Quantiles(ps...)
+	require.Len(t, values, len(ps))
+	for i, p := range ps {
+		assert.InEpsilon(t, expected[percentileIndex(t, p)][1], values[i], defaultRelativeAccuracy)
+	}
+	assert.Equal(t, []float64{0, 0}, NewHistogram().Quantiles(0.5, 0.99))
+}
+
+// percentileIndex returns the index of p in percentiles.
+func percentileIndex(t *testing.T, p float64) int {
+	for i, pv := range percentiles {
+		if pv == p {
+			return i
+		}
+	}
+	t.Fatalf("unexpected percentile %v", p)
+	return -1
+}
+
+func TestHistogramMerge(t *testing.T) {
+	all := NewHistogram()
+	parts := []*types.LatencyHistogram{}
+
+	for p := 0; p < 4; p++ {
+		h := NewHistogram()
+		for i := 0; i < 1000; i++ {
+			l := float64(p*1000+i) / 1000
+			h.Observe(l)
+			all.Observe(l)
+		}
+
+		// make sure that it's still mergeable after round trip
+		data, err := json.Marshal(h.Snapshot())
+		require.NoError(t, err)
+
+		s := &types.LatencyHistogram{}
+		require.NoError(t, json.Unmarshal(data, s))
+		parts = append(parts, s)
+	}
+
+	merged := MergeHistograms(parts...)
+	assert.Equal(t, all.Snapshot().Buckets, merged.Snapshot().Buckets)
+	assert.Equal(t, all.Snapshot().ZeroCount, merged.Snapshot().ZeroCount)
+	assert.Equal(t, all.PercentileLatencies(), merged.PercentileLatencies())
+	assert.InDelta(t, all.Snapshot().Sum, merged.Snapshot().Sum, 1e-6)
+
+	// different relative accuracy
+	other := &types.LatencyHistogram{
+		RelativeAccuracy: 0.05,
+		Count:            1,
+		Sum:              2,
+		Min:              2,
+		Max:              2,
+		Buckets:          [][2]int64{{int64(math.Ceil(math.Log(2) / math.Log(1.05/0.95))), 1}},
+	}
+	merged.Merge(other)
+	assert.Equal(t, int64(4001), merged.Count())
+	assert.Equal(t, float64(0), merged.Quantile(0))
+	assert.Equal(t, float64(3.999), merged.Quantile(1))
+	assert.InEpsilon(t, 2, merged.Quantile(2001.0/4001), 0.06)
+}
@@ -0,0 +1,117 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package metrics
+
+import (
+	"sync"
+	"time"
+)
+
+// Progress tracks live progress of benchmark so that it can be shown before
+// benchmark finishes.
+type Progress struct {
+	mu sync.Mutex
+	// start is the time of the first dispatched request.
+	start      time.Time
+	dispatched int64
+	finished   int64
+	errorStats map[string]int64
+	// lastTime and lastFinished are recorded by previous Next call.
+	lastTime     time.Time
+	lastFinished int64
+	// latencies only contains the latencies since previous Next call.
+	latencies *Histogram
+}
+
+// ProgressSnapshot is the progress at one moment.
+type ProgressSnapshot struct {
+	// Elapsed is the time since the first request was sent.
+	Elapsed time.Duration
+	// Finished is the number of requests which have got response or error.
+	Finished int64
+	// InFlight is the number of requests which have been sent but not
+	// finished.
+	InFlight int64
+	// QPS is the achieved rate since previous snapshot.
+	QPS float64
+	// P50 and P99 are latencies in seconds since previous snapshot. Both are
+	// zero if there is no succeeded request.
+	P50, P99 float64
+	// ErrorStats is the number of errors group by type since beginning.
+	ErrorStats map[string]int64
+}
+
+// NewProgress returns Progress which should be passed to ResponseMetric by
+// WithProgressOpt.
+func NewProgress() *Progress {
+	return &Progress{
+		errorStats: map[string]int64{},
+		latencies:  NewHistogram(),
+	}
+}
+
+// WithProgressOpt reports observations to Progress.
+func WithProgressOpt(p *Progress) ResponseMetricOpt {
+	return func(m *responseMetricImpl) {
+		m.progress = p
+	}
+}
+
+func (p *Progress) observeDispatch(now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.start.IsZero() {
+		p.start = now
+		p.lastTime = now
+	}
+	p.dispatched++
+}
+
+func (p *Progress) observeLatency(seconds float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.finished++
+	p.latencies.Observe(seconds)
+}
+
+func (p *Progress) observeFailure(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.finished++
+	p.errorStats[key]++
+}
+
+// Next returns the progress at now and starts next interval.
+func (p *Progress) Next(now time.Time) ProgressSnapshot {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	res := ProgressSnapshot{
+		Finished:   p.finished,
+		InFlight:   p.dispatched - p.finished,
+		ErrorStats: make(map[string]int64, len(p.errorStats)),
+	}
+	for k, v := range p.errorStats {
+		res.ErrorStats[k] = v
+	}
+	if p.start.IsZero() {
+		return res
+	}
+
+	res.Elapsed = now.Sub(p.start)
+	if d := now.Sub(p.lastTime).Seconds(); d > 0 {
+		res.QPS = float64(p.finished-p.lastFinished) / d
+	}
+	if p.latencies.Count() > 0 {
+		q := p.latencies.Quantiles(0.5, 0.99)
+		res.P50, res.P99 = q[0], q[1]
+	}
+
+	p.lastTime, p.lastFinished = now, p.finished
+	p.latencies = NewHistogram()
+	return res
+}
@@ -0,0 +1,44 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package metrics
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgress(t *testing.T) {
+	p := NewProgress()
+	m := NewResponseMetric(WithProgressOpt(p))
+
+	// not started yet
+	s := p.Next(time.Now())
+	assert.Equal(t, time.Duration(0), s.Elapsed)
+	assert.Equal(t, int64(0), s.Finished)
+
+	req := RequestInfo{Verb: "GET", Resource: "pods"}
+	for i := 0; i < 4; i++ {
+		m.ObserveDispatch(req, 0)
+	}
+	m.ObserveLatency(req, 0.1)
+	m.ObserveLatency(req, 0.1)
+	m.ObserveFailure(req, time.Now(), 0.1, errors.New("unknown"))
+
+	s = p.Next(time.Now().Add(time.Second))
+	assert.Equal(t, int64(3), s.Finished)
+	assert.Equal(t, int64(1), s.InFlight)
+	assert.Greater(t, s.QPS, 0.0)
+	assert.InDelta(t, 0.1, s.P50, 0.01)
+	assert.Equal(t, map[string]int64{"unknown/unknown": 1}, s.ErrorStats)
+
+	// latencies are reset for next interval while errors are accumulated
+	s = p.Next(time.Now().Add(2 * time.Second))
+	assert.Equal(t, int64(3), s.Finished)
+	assert.Equal(t, 0.0, s.P99)
+	assert.Equal(t, 0.0, s.QPS)
+	assert.Len(t, s.ErrorStats, 1)
+}
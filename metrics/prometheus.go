@@ -0,0 +1,110 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package metrics
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/Azure/kperf/api/types"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const prometheusNamespace = "kperf"
+
+// prometheusLatencyBuckets is aligned with kube-apiserver's
+// apiserver_request_duration_seconds so that both can be compared on the
+// same dashboard.
+var prometheusLatencyBuckets = []float64{
+	0.005, 0.025, 0.05, 0.1, 0.2, 0.4, 0.6, 0.8, 1.0, 1.25, 1.5, 2, 3,
+	4, 5, 6, 8, 10, 15, 20, 30, 45, 60,
+}
+
+// PrometheusMetrics exports live observations of ResponseMetric as
+// Prometheus metrics.
+type PrometheusMetrics struct {
+	requests      *prometheus.CounterVec
+	errors        *prometheus.CounterVec
+	latencies     *prometheus.HistogramVec
+	inflight      *prometheus.GaugeVec
+	limiterWait   prometheus.Histogram
+	receivedBytes prometheus.Counter
+}
+
+// NewPrometheusMetrics creates and registers Prometheus metrics.
+func NewPrometheusMetrics(reg prometheus.Registerer) (*PrometheusMetrics, error) {
+	labels := []string{"verb", "group", "resource", "scope", "tag"}
+
+	p := &PrometheusMetrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: prometheusNamespace,
+			Name:      "requests_total",
+			Help:      "Number of completed requests, including failed requests.",
+		}, labels),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: prometheusNamespace,
+			Name:      "request_errors_total",
+			Help:      "Number of failed requests group by error type and http code.",
+		}, append(labels, "type", "code")),
+		latencies: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: prometheusNamespace,
+			Name:      "request_duration_seconds",
+			Help:      "Latency of succeeded requests in seconds.",
+			Buckets:   prometheusLatencyBuckets,
+		}, labels),
+		inflight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: prometheusNamespace,
+			Name:      "inflight_requests",
+			Help:      "Number of requests which have been sent but not completed.",
+		}, labels),
+		limiterWait: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: prometheusNamespace,
+			Name:      "rate_limiter_wait_seconds",
+			Help:      "Time spent on waiting for client-side rate limiter in seconds.",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 10),
+		}),
+		receivedBytes: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: prometheusNamespace,
+			Name:      "received_bytes_total",
+			Help:      "Total bytes read from kube-apiserver.",
+		}),
+	}
+
+	for _, c := range []prometheus.Collector{
+		p.requests, p.errors, p.latencies, p.inflight, p.limiterWait, p.receivedBytes,
+	} {
+		if err := reg.Register(c); err != nil {
+			return nil, fmt.Errorf("failed to register prometheus metrics: %w", err)
+		}
+	}
+	return p, nil
+}
+
+// labelValues returns values for verb, group, resource, scope and tag labels.
+func labelValues(req RequestInfo, extra ...string) []string {
+	return append([]string{req.Verb, req.Group, req.Resource, req.Scope, req.Tag}, extra...)
+}
+
+func (p *PrometheusMetrics) observeDispatch(req RequestInfo, limiterWaitSeconds float64) {
+	p.limiterWait.Observe(limiterWaitSeconds)
+	p.inflight.WithLabelValues(labelValues(req)...).Inc()
+}
+
+func (p *PrometheusMetrics) observeLatency(req RequestInfo, seconds float64) {
+	p.inflight.WithLabelValues(labelValues(req)...).Dec()
+	p.requests.WithLabelValues(labelValues(req)...).Inc()
+	p.latencies.WithLabelValues(labelValues(req)...).Observe(seconds)
+}
+
+func (p *PrometheusMetrics) observeFailure(req RequestInfo, oerr types.ResponseError) {
+	code := ""
+	if oerr.Type == types.ResponseErrorTypeHTTP {
+		code = strconv.Itoa(oerr.Code)
+	}
+
+	p.inflight.WithLabelValues(labelValues(req)...).Dec()
+	p.requests.WithLabelValues(labelValues(req)...).Inc()
+	p.errors.WithLabelValues(labelValues(req, string(oerr.Type), code)...).Inc()
+}
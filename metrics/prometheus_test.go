@@ -0,0 +1,63 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+func TestResponseMetric_Prometheus(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	prom, err := NewPrometheusMetrics(reg)
+	require.NoError(t, err)
+
+	_, err = NewPrometheusMetrics(reg)
+	assert.Error(t, err, "should not register twice")
+
+	m := NewResponseMetric(WithPrometheusMetricsOpt(prom))
+
+	pods := RequestInfo{URL: "a", Verb: "LIST", Resource: "pods", Scope: "cluster", Tag: "list-pods"}
+	nodes := RequestInfo{URL: "b", Verb: "GET", Resource: "nodes", Scope: "resource"}
+
+	m.ObserveDispatch(pods, 0.01)
+	m.ObserveDispatch(pods, 0.01)
+	m.ObserveDispatch(nodes, 0)
+	assert.Equal(t, float64(2), testutil.ToFloat64(prom.inflight.WithLabelValues("LIST", "", "pods", "cluster", "list-pods")))
+
+	m.ObserveLatency(pods, 0.1)
+	m.ObserveFailure(pods, time.Now(), 0.2, apierrors.NewTooManyRequestsError("retry it later"))
+	m.ObserveReceivedBytes(100)
+
+	expected := `
+# HELP kperf_inflight_requests Number of requests which have been sent but not completed.
+# TYPE kperf_inflight_requests gauge
+kperf_inflight_requests{group="",resource="nodes",scope="resource",tag="",verb="GET"} 1
+kperf_inflight_requests{group="",resource="pods",scope="cluster",tag="list-pods",verb="LIST"} 0
+# HELP kperf_request_errors_total Number of failed requests group by error type and http code.
+# TYPE kperf_request_errors_total counter
+kperf_request_errors_total{code="429",group="",resource="pods",scope="cluster",tag="list-pods",type="http",verb="LIST"} 1
+# HELP kperf_requests_total Number of completed requests, including failed requests.
+# TYPE kperf_requests_total counter
+kperf_requests_total{group="",resource="pods",scope="cluster",tag="list-pods",verb="LIST"} 2
+# HELP kperf_received_bytes_total Total bytes read from kube-apiserver.
+# TYPE kperf_received_bytes_total counter
+kperf_received_bytes_total 100
+`
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected),
+		"kperf_inflight_requests",
+		"kperf_request_errors_total",
+		"kperf_requests_total",
+		"kperf_received_bytes_total",
+	))
+	assert.Equal(t, 1, testutil.CollectAndCount(prom.latencies))
+	assert.Equal(t, 1, testutil.CollectAndCount(prom.limiterWait))
+}
@@ -4,125 +4,435 @@
 package metrics
 
 import (
-	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/Azure/kperf/api/types"
+
+	"golang.org/x/net/http2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 )
 
+// RequestInfo describes the observed request.
+type RequestInfo struct {
+	// URL is the request's URL.
+	URL string
+	// Verb is the request's verb, for instance, LIST.
+	Verb string
+	// Group is the target resource's API group. Empty value means core.
+	Group string
+	// Resource is the target resource, for instance, pods.
+	Resource string
+	// Scope is cluster, namespace or resource.
+	Scope string
+	// Tag is the request's tag defined by load profile.
+	Tag string
+	// PriorityLevel is the UID of kube-apiserver's PriorityLevelConfiguration
+	// which handled the request. It's only known after response.
+	PriorityLevel string
+}
+
+// ResourceKey returns the key of verb, group, resource and scope, for
+// instance, "LIST deployments.apps namespace".
+func (r RequestInfo) ResourceKey() string {
+	resource := r.Resource
+	if r.Group != "" {
+		resource = resource + "." + r.Group
+	}
+	return fmt.Sprintf("%s %s %s", r.Verb, resource, r.Scope)
+}
+
 // ResponseMetric is a measurement related to http response.
 type ResponseMetric interface {
+	// ObserveDispatch observes that request is sent after waiting for rate
+	// limiter. The request should be observed by ObserveLatency or
+	// ObserveFailure when it completes.
+	ObserveDispatch(req RequestInfo, limiterWaitSeconds float64)
 	// ObserveLatency observes latency.
-	ObserveLatency(url string, seconds float64)
+	ObserveLatency(req RequestInfo, seconds float64)
 	// ObserveFailure observes failure response.
-	ObserveFailure(url string, now time.Time, seconds float64, err error)
+	ObserveFailure(req RequestInfo, now time.Time, seconds float64, err error)
 	// ObserveReceivedBytes observes the bytes read from apiserver.
 	ObserveReceivedBytes(bytes int64)
+	// ObserveFlowControl observes the request which was handled by
+	// kube-apiserver's PriorityLevelConfiguration and FlowSchema.
+	ObserveFlowControl(priorityLevel, flowSchema string, seconds float64, retries int, err error)
 	// Gather returns the summary.
 	Gather() types.ResponseStats
 }
 
 type responseMetricImpl struct {
 	mu              sync.Mutex
-	errors          *list.List
+	errorStats      map[string]int32
+	errorCounts     map[errorCountKey]int64
 	receivedBytes   int64
-	latenciesByURLs map[string]*list.List
+	latenciesByURLs map[string]*Histogram
+	statsByTag      map[string]*requestStats
+	statsByResource map[string]*requestStats
+	priorityLevels  map[string]*types.PriorityLevelStats
+	// plLatencies stores latency histogram for each priority level.
+	plLatencies map[string]*Histogram
+	// errors stores sampled examples of errors while errorStats and
+	// errorCounts count all the errors.
+	errors *errorReservoir
+	// timeSeries is nil if time-series statistics is disabled.
+	timeSeries *timeSeries
+	// prom is nil if Prometheus metrics is disabled.
+	prom *PrometheusMetrics
+	// progress is nil if live progress is disabled.
+	progress *Progress
+	// byURL is true if latencies are grouped by URL.
+	byURL bool
+}
+
+// ResponseMetricOpt is used to configure ResponseMetric.
+type ResponseMetricOpt func(*responseMetricImpl)
+
+// WithTimeSeriesIntervalOpt groups statistics by the interval (zero is
+// disabled).
+func WithTimeSeriesIntervalOpt(interval time.Duration) ResponseMetricOpt {
+	return func(m *responseMetricImpl) {
+		if interval > 0 {
+			m.timeSeries = newTimeSeries(interval)
+		}
+	}
+}
+
+// WithPrometheusMetricsOpt exports observations as Prometheus metrics.
+func WithPrometheusMetricsOpt(prom *PrometheusMetrics) ResponseMetricOpt {
+	return func(m *responseMetricImpl) {
+		m.prom = prom
+	}
 }
 
-func NewResponseMetric() ResponseMetric {
-	return &responseMetricImpl{
-		errors:          list.New(),
-		latenciesByURLs: map[string]*list.List{},
+// WithErrorSamplesOpt keeps at most n sampled error examples (zero means no
+// example). The default value is DefaultErrorSamples.
+func WithErrorSamplesOpt(n int) ResponseMetricOpt {
+	return func(m *responseMetricImpl) {
+		m.errors = newErrorReservoir(n)
+	}
+}
+
+// WithLatenciesByURLOpt groups latencies by request's URL as well. It's
+// disabled by default because URLs with different query parameters explode
+// into many keys.
+func WithLatenciesByURLOpt(enabled bool) ResponseMetricOpt {
+	return func(m *responseMetricImpl) {
+		m.byURL = enabled
+	}
+}
+
+func NewResponseMetric(opts ...ResponseMetricOpt) ResponseMetric {
+	m := &responseMetricImpl{
+		errors:          newErrorReservoir(DefaultErrorSamples),
+		errorStats:      map[string]int32{},
+		errorCounts:     map[errorCountKey]int64{},
+		latenciesByURLs: map[string]*Histogram{},
+		statsByTag:      map[string]*requestStats{},
+		statsByResource: map[string]*requestStats{},
+		priorityLevels:  map[string]*types.PriorityLevelStats{},
+		plLatencies:     map[string]*Histogram{},
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// ObserveDispatch implements ResponseMetric.
+func (m *responseMetricImpl) ObserveDispatch(req RequestInfo, limiterWaitSeconds float64) {
+	if m.prom != nil {
+		m.prom.observeDispatch(req, limiterWaitSeconds)
+	}
+	if m.progress != nil {
+		m.progress.observeDispatch(time.Now())
 	}
 }
 
 // ObserveLatency implements ResponseMetric.
-func (m *responseMetricImpl) ObserveLatency(url string, seconds float64) {
+func (m *responseMetricImpl) ObserveLatency(req RequestInfo, seconds float64) {
+	if m.prom != nil {
+		m.prom.observeLatency(req, seconds)
+	}
+	if m.progress != nil {
+		m.progress.observeLatency(seconds)
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	l, ok := m.latenciesByURLs[url]
-	if !ok {
-		m.latenciesByURLs[url] = list.New()
-		l = m.latenciesByURLs[url]
+	for _, stats := range m.requestStatsFor(req) {
+		stats.total++
+		stats.latencies.Observe(seconds)
+	}
+
+	if m.byURL {
+		h, ok := m.latenciesByURLs[req.URL]
+		if !ok {
+			h = NewHistogram()
+			m.latenciesByURLs[req.URL] = h
+		}
+		h.Observe(seconds)
+	}
+
+	if m.timeSeries != nil {
+		b := m.timeSeries.bucket(time.Now())
+		b.total++
+		b.latencies.Observe(seconds)
 	}
-	l.PushBack(seconds)
 }
 
 // ObserveFailure implements ResponseMetric.
-func (m *responseMetricImpl) ObserveFailure(url string, now time.Time, seconds float64, err error) {
+func (m *responseMetricImpl) ObserveFailure(req RequestInfo, now time.Time, seconds float64, err error) {
 	if err == nil {
 		return
 	}
 
+	oerr := newResponseError(req, now, seconds, err)
+	if m.prom != nil {
+		m.prom.observeFailure(req, oerr)
+	}
+
+	key := errorStatKey(oerr)
+	if m.progress != nil {
+		m.progress.observeFailure(key)
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	oerr := types.ResponseError{
-		URL:       url,
-		Timestamp: now,
-		Duration:  seconds,
+	m.errors.add(oerr)
+
+	m.errorStats[key]++
+	m.errorCounts[errorCountKey{typ: oerr.Type, code: oerr.Code, tag: oerr.Tag}]++
+	for _, stats := range m.requestStatsFor(req) {
+		stats.total++
+		stats.errorStats[key]++
 	}
 
-	// HTTP Code -> HTTP2 -> Connection -> Unknown
-	code := codeFromHTTP(err)
-	http2Err, isHTTP2Err := isHTTP2Error(err)
-	connErr, isConnErr := isConnectionError(err)
-	switch {
-	case code != 0:
-		oerr.Type = types.ResponseErrorTypeHTTP
-		oerr.Code = code
-	case isHTTP2Err:
-		oerr.Type = types.ResponseErrorTypeHTTP2Protocol
-		oerr.Message = http2Err
-	case isConnErr:
-		oerr.Type = types.ResponseErrorTypeConnection
-		oerr.Message = connErr
-	default:
-		oerr.Type = types.ResponseErrorTypeUnknown
-		oerr.Message = err.Error()
+	if m.timeSeries != nil {
+		b := m.timeSeries.bucket(now)
+		b.total++
+		b.errorStats[key]++
 	}
-	m.errors.PushBack(oerr)
+}
+
+// requestStatsFor returns the statistics which the request belongs to.
+//
+// NOTE: The caller should hold the lock.
+func (m *responseMetricImpl) requestStatsFor(req RequestInfo) []*requestStats {
+	res := make([]*requestStats, 0, 2)
+
+	key := req.ResourceKey()
+	stats, ok := m.statsByResource[key]
+	if !ok {
+		stats = newRequestStats(req)
+		m.statsByResource[key] = stats
+	}
+	res = append(res, stats)
+
+	if req.Tag != "" {
+		stats, ok := m.statsByTag[req.Tag]
+		if !ok {
+			stats = newRequestStats(RequestInfo{})
+			m.statsByTag[req.Tag] = stats
+		}
+		res = append(res, stats)
+	}
+	return res
 }
 
 // ObserveReceivedBytes implements ResponseMetric.
 func (m *responseMetricImpl) ObserveReceivedBytes(bytes int64) {
 	atomic.AddInt64(&m.receivedBytes, bytes)
+	if m.prom != nil {
+		m.prom.receivedBytes.Add(float64(bytes))
+	}
+
+	if m.timeSeries != nil {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		m.timeSeries.bucket(time.Now()).receivedBytes += bytes
+	}
+}
+
+// ObserveFlowControl implements ResponseMetric.
+func (m *responseMetricImpl) ObserveFlowControl(priorityLevel, flowSchema string, seconds float64, retries int, err error) {
+	if priorityLevel == "" {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats, ok := m.priorityLevels[priorityLevel]
+	if !ok {
+		stats = &types.PriorityLevelStats{
+			FlowSchemas: map[string]int{},
+		}
+		m.priorityLevels[priorityLevel] = stats
+	}
+
+	stats.Total++
+	stats.Retries += retries
+	if flowSchema != "" {
+		stats.FlowSchemas[flowSchema]++
+	}
+
+	switch {
+	case err == nil:
+		h, ok := m.plLatencies[priorityLevel]
+		if !ok {
+			h = NewHistogram()
+			m.plLatencies[priorityLevel] = h
+		}
+		h.Observe(seconds)
+	case codeFromHTTP(err) == http.StatusTooManyRequests:
+		stats.TooManyRequests++
+	}
 }
 
 // Gather implements ResponseMetric.
 func (m *responseMetricImpl) Gather() types.ResponseStats {
+	errs, errStats, errCounts := m.dumpErrors()
 	return types.ResponseStats{
-		Errors:             m.dumpErrors(),
-		LatenciesByURL:     m.dumpLatencies(),
+		Errors:             errs,
+		ErrorStats:         errStats,
+		ErrorCounts:        errCounts,
+		HistogramsByURL:    m.dumpLatencies(),
+		StatsByTag:         m.dumpRequestStats(m.statsByTag),
+		StatsByResource:    m.dumpRequestStats(m.statsByResource),
 		TotalReceivedBytes: atomic.LoadInt64(&m.receivedBytes),
+		PriorityLevels:     m.dumpPriorityLevels(),
+		TimeSeries:         m.dumpTimeSeries(),
 	}
 }
 
-func (m *responseMetricImpl) dumpLatencies() map[string][]float64 {
+func (m *responseMetricImpl) dumpTimeSeries() []types.TimeSeriesBucket {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	res := make(map[string][]float64)
-	for u, latencies := range m.latenciesByURLs {
-		res[u] = make([]float64, 0, latencies.Len())
+	if m.timeSeries == nil {
+		return nil
+	}
+	return m.timeSeries.dump()
+}
 
-		for e := latencies.Front(); e != nil; e = e.Next() {
-			res[u] = append(res[u], e.Value.(float64))
-		}
+func (m *responseMetricImpl) dumpPriorityLevels() map[string]*types.PriorityLevelStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	res := make(map[string]*types.PriorityLevelStats, len(m.priorityLevels))
+	MergePriorityLevelStats(res, m.priorityLevels)
+	for name, h := range m.plLatencies {
+		res[name].Histogram = h.Snapshot()
 	}
 	return res
 }
 
-func (m *responseMetricImpl) dumpErrors() []types.ResponseError {
+func (m *responseMetricImpl) dumpRequestStats(stats map[string]*requestStats) map[string]*types.RequestStats {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	res := make([]types.ResponseError, 0, m.errors.Len())
-	for e := m.errors.Front(); e != nil; e = e.Next() {
-		res = append(res, e.Value.(types.ResponseError))
+	res := make(map[string]*types.RequestStats, len(stats))
+	for key, s := range stats {
+		res[key] = s.snapshot()
+	}
+	return res
+}
+
+func (m *responseMetricImpl) dumpLatencies() map[string]*types.LatencyHistogram {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.byURL {
+		return nil
+	}
+
+	res := make(map[string]*types.LatencyHistogram, len(m.latenciesByURLs))
+	for u, h := range m.latenciesByURLs {
+		res[u] = h.Snapshot()
 	}
 	return res
 }
+
+func (m *responseMetricImpl) dumpErrors() ([]types.ResponseError, map[string]int32, []types.ErrorCount) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	errStats := make(map[string]int32, len(m.errorStats))
+	mergeErrorStats(errStats, m.errorStats)
+	return m.errors.dump(), errStats, dumpErrorCounts(m.errorCounts)
+}
+
+// newResponseError classifies err into types.ResponseError.
+func newResponseError(req RequestInfo, now time.Time, seconds float64, err error) types.ResponseError {
+	oerr := types.ResponseError{
+		URL:       req.URL,
+		Timestamp: now,
+		Duration:  seconds,
+		Tag:       req.Tag,
+	}
+
+	// HTTP Code -> Context -> HTTP2 -> DNS -> TLS -> Connection -> Unknown
+	code := codeFromHTTP(err)
+	http2Err, isHTTP2Err := isHTTP2Error(err)
+	dnsErr, isDNSErr := isDNSError(err)
+	tlsErr, isTLSErr := isTLSError(err)
+	connErr, isConnErr := isConnectionError(err)
+	switch {
+	case code != 0:
+		oerr.Type = types.ResponseErrorTypeHTTP
+		oerr.Code = code
+		oerr.Reason, oerr.Message = statusFromError(err)
+
+		// NOTE: APIPriorityAndFairness sets flowcontrol headers even if
+		// the request is rejected.
+		if isFilterRejection(err) {
+			oerr.Type = types.ResponseErrorTypeMaxInflightRejected
+			if req.PriorityLevel != "" {
+				oerr.Type = types.ResponseErrorTypeAPFRejected
+			}
+		}
+	case isContextCanceled(err):
+		oerr.Type = types.ResponseErrorTypeContextCanceled
+		oerr.Message = context.Canceled.Error()
+	case isClientTimeout(err):
+		oerr.Type = types.ResponseErrorTypeClientTimeout
+		oerr.Message = context.DeadlineExceeded.Error()
+	case isHTTP2RefusedStream(err):
+		oerr.Type = types.ResponseErrorTypeHTTP2RefusedStream
+		oerr.Message = http2.ErrCodeRefusedStream.String()
+	case isHTTP2Err:
+		oerr.Type = types.ResponseErrorTypeHTTP2Protocol
+		oerr.Message = http2Err
+	case isDNSErr:
+		oerr.Type = types.ResponseErrorTypeDNS
+		oerr.Message = dnsErr
+	case isTLSErr:
+		oerr.Type = types.ResponseErrorTypeTLS
+		oerr.Message = tlsErr
+	case isConnErr:
+		oerr.Type = types.ResponseErrorTypeConnection
+		oerr.Message = connErr
+	default:
+		oerr.Type = types.ResponseErrorTypeUnknown
+		oerr.Message = err.Error()
+	}
+	return oerr
+}
+
+// statusFromError returns reason and message from apiserver's Status if any.
+func statusFromError(err error) (reason, message string) {
+	if status, ok := err.(apierrors.APIStatus); ok || errors.As(err, &status) {
+		s := status.Status()
+		return string(s.Reason), s.Message
+	}
+	return "", ""
+}
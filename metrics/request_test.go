@@ -5,9 +5,13 @@ package metrics
 
 import (
 	"context"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"io"
+	"net"
+	"net/http"
+	"net/url"
 	"syscall"
 	"testing"
 	"time"
@@ -15,8 +19,10 @@ import (
 	"github.com/Azure/kperf/api/types"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"golang.org/x/net/http2"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 func TestResponseMetric_ObserveFailure(t *testing.T) {
@@ -30,6 +36,8 @@ func TestResponseMetric_ObserveFailure(t *testing.T) {
 			Duration:  dur.Seconds(),
 			Type:      types.ResponseErrorTypeHTTP,
 			Code:      429,
+			Reason:    "TooManyRequests",
+			Message:   "Too many requests: retry it later",
 		},
 		{
 			URL:       "1",
@@ -37,6 +45,8 @@ func TestResponseMetric_ObserveFailure(t *testing.T) {
 			Duration:  dur.Seconds(),
 			Type:      types.ResponseErrorTypeHTTP,
 			Code:      500,
+			Reason:    "InternalError",
+			Message:   "Internal error occurred: oops",
 		},
 		{
 			URL:       "2",
@@ -44,6 +54,8 @@ func TestResponseMetric_ObserveFailure(t *testing.T) {
 			Duration:  dur.Seconds(),
 			Type:      types.ResponseErrorTypeHTTP,
 			Code:      504,
+			Reason:    "Timeout",
+			Message:   "Timeout: timeout in test",
 		},
 		{
 			URL:       "3",
@@ -98,7 +110,7 @@ func TestResponseMetric_ObserveFailure(t *testing.T) {
 			URL:       "10",
 			Timestamp: observedAt,
 			Duration:  dur.Seconds(),
-			Type:      types.ResponseErrorTypeConnection,
+			Type:      types.ResponseErrorTypeClientTimeout,
 			Message:   "context deadline exceeded",
 		},
 		{
@@ -156,7 +168,7 @@ func TestResponseMetric_ObserveFailure(t *testing.T) {
 		// net
 		errTLSHandshakeTimeout,
 		fmt.Errorf("oops: %w", errTLSHandshakeTimeout),
-		context.DeadlineExceeded, // i/o timeout
+		context.DeadlineExceeded, // client-side timeout
 		fmt.Errorf("oops: %w", syscall.ECONNRESET),
 		fmt.Errorf("oops: %w", syscall.ECONNREFUSED),
 		fmt.Errorf("oops: %w", io.ErrUnexpectedEOF),
@@ -166,8 +178,185 @@ func TestResponseMetric_ObserveFailure(t *testing.T) {
 
 	m := NewResponseMetric()
 	for idx, err := range errs {
-		m.ObserveFailure(fmt.Sprintf("%d", idx), observedAt, dur.Seconds(), err)
+		m.ObserveFailure(RequestInfo{URL: fmt.Sprintf("%d", idx)}, observedAt, dur.Seconds(), err)
 	}
 	errors := m.Gather().Errors
 	assert.Equal(t, expectedErrors, errors)
 }
+
+func TestNewResponseError(t *testing.T) {
+	filterRejection := apierrors.NewGenericServerResponse(http.StatusTooManyRequests, "GET",
+		schema.GroupResource{}, "", "Too many requests, please try again later.", 1, true)
+
+	for _, tc := range []struct {
+		name          string
+		priorityLevel string
+		err           error
+		typ           types.ResponseErrorType
+		code          int
+		message       string
+	}{
+		{
+			name:          "apf rejection",
+			priorityLevel: "uid",
+			err:           filterRejection,
+			typ:           types.ResponseErrorTypeAPFRejected,
+			code:          http.StatusTooManyRequests,
+		},
+		{
+			name: "max-inflight rejection",
+			err:  fmt.Errorf("oops: %w", filterRejection),
+			typ:  types.ResponseErrorTypeMaxInflightRejected,
+			code: http.StatusTooManyRequests,
+		},
+		{
+			name:          "429 from storage",
+			priorityLevel: "uid",
+			err:           apierrors.NewTooManyRequestsError("retry it later"),
+			typ:           types.ResponseErrorTypeHTTP,
+			code:          http.StatusTooManyRequests,
+		},
+		{
+			name: "server timeout",
+			err:  apierrors.NewTimeoutError("timeout in test", 100),
+			typ:  types.ResponseErrorTypeHTTP,
+			code: http.StatusGatewayTimeout,
+		},
+		{
+			name:    "client timeout",
+			err:     &url.Error{Op: "Get", URL: "/api", Err: context.DeadlineExceeded},
+			typ:     types.ResponseErrorTypeClientTimeout,
+			message: "context deadline exceeded",
+		},
+		{
+			name:    "context canceled",
+			err:     fmt.Errorf("oops: %w", context.Canceled),
+			typ:     types.ResponseErrorTypeContextCanceled,
+			message: "context canceled",
+		},
+		{
+			name:    "http2 refused stream",
+			err:     http2.StreamError{StreamID: 1, Code: http2.ErrCodeRefusedStream},
+			typ:     types.ResponseErrorTypeHTTP2RefusedStream,
+			message: "REFUSED_STREAM",
+		},
+		{
+			name:    "dns",
+			err:     &url.Error{Op: "Get", URL: "/api", Err: &net.DNSError{Err: "no such host", Name: "x", IsNotFound: true}},
+			typ:     types.ResponseErrorTypeDNS,
+			message: "no such host",
+		},
+		{
+			name:    "tls",
+			err:     &url.Error{Op: "Get", URL: "/api", Err: x509.UnknownAuthorityError{}},
+			typ:     types.ResponseErrorTypeTLS,
+			message: "x509: certificate signed by unknown authority",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			oerr := newResponseError(RequestInfo{PriorityLevel: tc.priorityLevel}, time.Now(), 1, tc.err)
+			assert.Equal(t, tc.typ, oerr.Type)
+			assert.Equal(t, tc.code, oerr.Code)
+			if tc.code == 0 {
+				assert.Equal(t, tc.message, oerr.Message)
+			}
+		})
+	}
+}
+
+func TestResponseMetric_BoundedErrors(t *testing.T) {
+	m := NewResponseMetric(WithErrorSamplesOpt(10))
+
+	listPods := RequestInfo{URL: "/api/v1/pods", Verb: "LIST", Resource: "pods", Scope: "cluster", Tag: "list"}
+	for i := 0; i < 1000; i++ {
+		m.ObserveFailure(listPods, time.Now(), 0.1, apierrors.NewTooManyRequestsError("retry it later"))
+	}
+	m.ObserveFailure(RequestInfo{URL: "/api/v1/nodes"}, time.Now(), 0.1, apierrors.NewInternalError(errors.New("oops")))
+
+	stats := m.Gather()
+	require.Len(t, stats.Errors, 10)
+	for _, e := range stats.Errors {
+		assert.Equal(t, types.ResponseErrorTypeHTTP, e.Type)
+		assert.NotEmpty(t, e.Reason)
+	}
+	assert.Equal(t, map[string]int32{"http/429": 1000, "http/500": 1}, stats.ErrorStats)
+	assert.Equal(t, []types.ErrorCount{
+		{Type: types.ResponseErrorTypeHTTP, Code: 429, Tag: "list", Count: 1000},
+		{Type: types.ResponseErrorTypeHTTP, Code: 500, Count: 1},
+	}, stats.ErrorCounts)
+
+	assert.Equal(t, []types.ErrorCount{
+		{Type: types.ResponseErrorTypeHTTP, Code: 429, Tag: "list", Count: 1001},
+		{Type: types.ResponseErrorTypeHTTP, Code: 500, Count: 1},
+	}, MergeErrorCounts(stats.ErrorCounts, []types.ErrorCount{
+		{Type: types.ResponseErrorTypeHTTP, Code: 429, Tag: "list", Count: 1},
+	}))
+	assert.Len(t, SampleErrors(5, stats.Errors, stats.Errors), 5)
+}
+
+func TestResponseMetric_ObserveFlowControl(t *testing.T) {
+	m := NewResponseMetric()
+
+	m.ObserveFlowControl("pl-a", "fs-a", 0.1, 0, nil)
+	m.ObserveFlowControl("pl-a", "fs-b", 0.2, 2, nil)
+	m.ObserveFlowControl("pl-a", "fs-a", 0.3, 3, apierrors.NewTooManyRequestsError("retry it later"))
+	m.ObserveFlowControl("pl-b", "fs-c", 0.4, 0, apierrors.NewInternalError(errors.New("oops")))
+	// no flowcontrol headers
+	m.ObserveFlowControl("", "", 0.5, 0, nil)
+
+	expected := map[string]*types.PriorityLevelStats{
+		"pl-a": {
+			Total:           3,
+			FlowSchemas:     map[string]int{"fs-a": 2, "fs-b": 1},
+			TooManyRequests: 1,
+			Retries:         5,
+		},
+		"pl-b": {
+			Total:       1,
+			FlowSchemas: map[string]int{"fs-c": 1},
+		},
+	}
+	got := m.Gather().PriorityLevels
+	require.NotNil(t, got["pl-a"].Histogram)
+	assert.Equal(t, int64(2), got["pl-a"].Histogram.Count)
+	assert.Nil(t, got["pl-b"].Histogram)
+
+	got["pl-a"].Histogram = nil
+	assert.Equal(t, expected, got)
+}
+
+func TestResponseMetric_StatsByTagAndResource(t *testing.T) {
+	listPods := RequestInfo{URL: "/api/v1/pods", Verb: "LIST", Resource: "pods", Scope: "cluster", Tag: "list"}
+	getPod := RequestInfo{URL: "/api/v1/namespaces/default/pods/x", Verb: "GET", Resource: "pods", Scope: "resource"}
+	listDeploys := RequestInfo{URL: "/apis/apps/v1/deployments", Verb: "LIST", Group: "apps", Resource: "deployments", Scope: "cluster", Tag: "list"}
+
+	m := NewResponseMetric()
+	m.ObserveLatency(listPods, 0.1)
+	m.ObserveLatency(listPods, 0.2)
+	m.ObserveLatency(getPod, 0.3)
+	m.ObserveFailure(listDeploys, time.Now(), 0.4, apierrors.NewTooManyRequestsError("retry it later"))
+
+	stats := m.Gather()
+	assert.Nil(t, stats.HistogramsByURL)
+
+	require.Len(t, stats.StatsByResource, 3)
+	pods := stats.StatsByResource["LIST pods cluster"]
+	require.NotNil(t, pods)
+	assert.Equal(t, 2, pods.Total)
+	assert.Equal(t, int64(2), pods.Histogram.Count)
+
+	deploys := stats.StatsByResource["LIST deployments.apps cluster"]
+	require.NotNil(t, deploys)
+	assert.Equal(t, "apps", deploys.Group)
+	assert.Equal(t, 1, deploys.Total)
+	assert.Equal(t, map[string]int32{"http/429": 1}, deploys.ErrorStats)
+	assert.Nil(t, deploys.Histogram)
+
+	require.Len(t, stats.StatsByTag, 1)
+	assert.Equal(t, 3, stats.StatsByTag["list"].Total)
+	assert.Equal(t, int64(2), stats.StatsByTag["list"].Histogram.Count)
+
+	m = NewResponseMetric(WithLatenciesByURLOpt(true))
+	m.ObserveLatency(listPods, 0.1)
+	assert.Len(t, m.Gather().HistogramsByURL, 1)
+}
@@ -0,0 +1,298 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package metrics
+
+import (
+	"bufio"
+	"bytes"
+	"math"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Azure/kperf/api/types"
+)
+
+// SelfMonitor samples runner process's own resource usage periodically.
+type SelfMonitor struct {
+	interval time.Duration
+
+	mu            sync.Mutex
+	startedAt     time.Time
+	startCPU      float64
+	lastCPU       float64
+	lastSampledAt time.Time
+	maxCPUCores   float64
+	maxGoroutines int
+	startThrottle float64
+	startGC       debug.GCStats
+	limiterLags   *Histogram
+
+	stopCh chan struct{}
+	doneCh chan struct{}
+}
+
+// NewSelfMonitor returns SelfMonitor which samples every interval.
+func NewSelfMonitor(interval time.Duration) *SelfMonitor {
+	return &SelfMonitor{
+		interval:    interval,
+		limiterLags: NewHistogram(),
+		stopCh:      make(chan struct{}),
+		doneCh:      make(chan struct{}),
+	}
+}
+
+// Start starts to sample in background.
+func (m *SelfMonitor) Start() {
+	m.mu.Lock()
+	m.startedAt = time.Now()
+	m.startCPU = processCPUSeconds()
+	m.lastCPU, m.lastSampledAt = m.startCPU, m.startedAt
+	m.startThrottle, _ = cgroupCPUThrottledSeconds()
+	debug.ReadGCStats(&m.startGC)
+	m.mu.Unlock()
+
+	go func() {
+		defer close(m.doneCh)
+
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-m.stopCh:
+				return
+			case <-ticker.C:
+				m.sample()
+			}
+		}
+	}()
+}
+
+// ObserveLimiterLag observes delay between intended and actual dispatch
+// time.
+func (m *SelfMonitor) ObserveLimiterLag(seconds float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.limiterLags.Observe(seconds)
+}
+
+// Stop stops sampling and returns the summary.
+func (m *SelfMonitor) Stop() *types.RunnerSelfStats {
+	close(m.stopCh)
+	<-m.doneCh
+	m.sample()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	elapsed := time.Since(m.startedAt).Seconds()
+	cpuSeconds := m.lastCPU - m.startCPU
+
+	res := &types.RunnerSelfStats{
+		CPUSeconds:            cpuSeconds,
+		MaxCPUCores:           m.maxCPUCores,
+		AvailableCPUCores:     float64(runtime.GOMAXPROCS(0)),
+		MaxRSSBytes:           processMaxRSSBytes(),
+		MaxGoroutines:         m.maxGoroutines,
+		PercentileLimiterLags: m.limiterLags.PercentileLatencies(),
+	}
+	if elapsed > 0 {
+		res.AvgCPUCores = cpuSeconds / elapsed
+	}
+	if quota, ok := cgroupCPUQuotaCores(); ok {
+		res.AvailableCPUCores = quota
+	}
+	if throttled, ok := cgroupCPUThrottledSeconds(); ok {
+		res.ThrottledSeconds = throttled - m.startThrottle
+	}
+
+	gcStats := debug.GCStats{PauseQuantiles: make([]time.Duration, 5)}
+	debug.ReadGCStats(&gcStats)
+	res.NumGC = gcStats.NumGC - m.startGC.NumGC
+	res.GCPauseSeconds = (gcStats.PauseTotal - m.startGC.PauseTotal).Seconds()
+	if res.NumGC > 0 {
+		// NOTE: The last one is the maximum pause of recent cycles.
+		res.MaxGCPauseSeconds = gcStats.PauseQuantiles[len(gcStats.PauseQuantiles)-1].Seconds()
+	}
+	return res
+}
+
+// sample records CPU usage and the number of goroutines since last sample.
+func (m *SelfMonitor) sample() {
+	now := time.Now()
+	cpu := processCPUSeconds()
+	goroutines := runtime.NumGoroutine()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if elapsed := now.Sub(m.lastSampledAt).Seconds(); elapsed > 0 {
+		if cores := (cpu - m.lastCPU) / elapsed; cores > m.maxCPUCores {
+			m.maxCPUCores = cores
+		}
+	}
+	m.lastCPU, m.lastSampledAt = cpu, now
+
+	if goroutines > m.maxGoroutines {
+		m.maxGoroutines = goroutines
+	}
+}
+
+// processCPUSeconds returns user and system CPU time consumed by current
+// process.
+func processCPUSeconds() float64 {
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		return 0
+	}
+
+	toSeconds := func(tv syscall.Timeval) float64 {
+		return float64(tv.Sec) + float64(tv.Usec)/1e6
+	}
+	return toSeconds(usage.Utime) + toSeconds(usage.Stime)
+}
+
+// processMaxRSSBytes returns peak resident set size of current process.
+func processMaxRSSBytes() int64 {
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		return 0
+	}
+	// NOTE: Linux reports it in kilobytes.
+	return int64(usage.Maxrss) * 1024
+}
+
+const (
+	// cgroupV2Dir is the mountpoint of cgroup v2 in container.
+	cgroupV2Dir = "/sys/fs/cgroup"
+	// cgroupV1CPUDir is the mountpoint of cgroup v1's cpu controller in
+	// container.
+	cgroupV1CPUDir = "/sys/fs/cgroup/cpu,cpuacct"
+)
+
+// cgroupCPUQuotaCores returns CPU quota in cores. It returns false if there
+// is no quota or cgroup is unavailable.
+func cgroupCPUQuotaCores() (float64, bool) {
+	// cgroup v2: "$MAX $PERIOD" or "max $PERIOD"
+	if data, err := os.ReadFile(cgroupV2Dir + "/cpu.max"); err == nil {
+		fields := strings.Fields(string(data))
+		if len(fields) != 2 || fields[0] == "max" {
+			return 0, false
+		}
+		return ratioOf(fields[0], fields[1])
+	}
+
+	// cgroup v1: -1 means no quota.
+	quota, err := os.ReadFile(cgroupV1CPUDir + "/cpu.cfs_quota_us")
+	if err != nil {
+		return 0, false
+	}
+	period, err := os.ReadFile(cgroupV1CPUDir + "/cpu.cfs_period_us")
+	if err != nil {
+		return 0, false
+	}
+	return ratioOf(strings.TrimSpace(string(quota)), strings.TrimSpace(string(period)))
+}
+
+// cgroupCPUThrottledSeconds returns total throttled time of the cgroup. It
+// returns false if cgroup is unavailable.
+func cgroupCPUThrottledSeconds() (float64, bool) {
+	if v, ok := readCgroupStat(cgroupV2Dir+"/cpu.stat", "throttled_usec"); ok {
+		return v / 1e6, true
+	}
+	if v, ok := readCgroupStat(cgroupV1CPUDir+"/cpu.stat", "throttled_time"); ok {
+		return v / 1e9, true
+	}
+	return 0, false
+}
+
+// readCgroupStat returns the value of key in cgroup's flat keyed file.
+func readCgroupStat(path string, key string) (float64, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, false
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || fields[0] != key {
+			continue
+		}
+
+		v, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return 0, false
+		}
+		return v, true
+	}
+	return 0, false
+}
+
+// ratioOf returns a / b. It returns false if a or b is invalid or not
+// positive.
+func ratioOf(a, b string) (float64, bool) {
+	x, err := strconv.ParseFloat(a, 64)
+	if err != nil || x <= 0 {
+		return 0, false
+	}
+	y, err := strconv.ParseFloat(b, 64)
+	if err != nil || y <= 0 {
+		return 0, false
+	}
+	return x / y, true
+}
+
+// MergeRunnerSelfStats merges runners' own resource usage. It sums CPU time,
+// throttled time and GC, and takes the maximum of the others so that the
+// busiest runner stands out. AvailableCPUCores is the minimum, which is the
+// smallest runner. Percentile limiter lags are the maximum of each
+// percentile since they can't be merged exactly. It returns nil if there is
+// no stats.
+func MergeRunnerSelfStats(stats ...*types.RunnerSelfStats) *types.RunnerSelfStats {
+	var res *types.RunnerSelfStats
+	for _, s := range stats {
+		if s == nil {
+			continue
+		}
+		if res == nil {
+			res = &types.RunnerSelfStats{AvailableCPUCores: s.AvailableCPUCores}
+		}
+
+		res.CPUSeconds += s.CPUSeconds
+		res.ThrottledSeconds += s.ThrottledSeconds
+		res.NumGC += s.NumGC
+		res.GCPauseSeconds += s.GCPauseSeconds
+
+		res.AvgCPUCores = math.Max(res.AvgCPUCores, s.AvgCPUCores)
+		res.MaxCPUCores = math.Max(res.MaxCPUCores, s.MaxCPUCores)
+		res.AvailableCPUCores = math.Min(res.AvailableCPUCores, s.AvailableCPUCores)
+		res.MaxGCPauseSeconds = math.Max(res.MaxGCPauseSeconds, s.MaxGCPauseSeconds)
+		if s.MaxRSSBytes > res.MaxRSSBytes {
+			res.MaxRSSBytes = s.MaxRSSBytes
+		}
+		if s.MaxGoroutines > res.MaxGoroutines {
+			res.MaxGoroutines = s.MaxGoroutines
+		}
+
+		if res.PercentileLimiterLags == nil && len(s.PercentileLimiterLags) > 0 {
+			res.PercentileLimiterLags = make([][2]float64, len(s.PercentileLimiterLags))
+			copy(res.PercentileLimiterLags, s.PercentileLimiterLags)
+			continue
+		}
+		for idx := range s.PercentileLimiterLags {
+			if idx < len(res.PercentileLimiterLags) && res.PercentileLimiterLags[idx][0] == s.PercentileLimiterLags[idx][0] {
+				res.PercentileLimiterLags[idx][1] = math.Max(res.PercentileLimiterLags[idx][1], s.PercentileLimiterLags[idx][1])
+			}
+		}
+	}
+	return res
+}
@@ -0,0 +1,91 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Azure/kperf/api/types"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelfMonitor(t *testing.T) {
+	m := NewSelfMonitor(10 * time.Millisecond)
+	m.Start()
+
+	for i := 0; i < 10; i++ {
+		m.ObserveLimiterLag(float64(i) / 10)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	stats := m.Stop()
+	assert.Positive(t, stats.AvailableCPUCores)
+	assert.Positive(t, stats.MaxGoroutines)
+	assert.GreaterOrEqual(t, stats.CPUSeconds, float64(0))
+
+	p99, ok := PercentileValue(stats.PercentileLimiterLags, 0.99)
+	assert.True(t, ok)
+	assert.InDelta(t, 0.9, p99, 0.9*0.02)
+}
+
+func TestRatioOf(t *testing.T) {
+	v, ok := ratioOf("200000", "100000")
+	assert.True(t, ok)
+	assert.Equal(t, float64(2), v)
+
+	_, ok = ratioOf("-1", "100000")
+	assert.False(t, ok)
+
+	_, ok = ratioOf("max", "100000")
+	assert.False(t, ok)
+}
+
+func TestMergeRunnerSelfStats(t *testing.T) {
+	assert.Nil(t, MergeRunnerSelfStats())
+	assert.Nil(t, MergeRunnerSelfStats(nil, nil))
+
+	merged := MergeRunnerSelfStats(
+		&types.RunnerSelfStats{
+			CPUSeconds:            10,
+			AvgCPUCores:           1,
+			MaxCPUCores:           1.5,
+			AvailableCPUCores:     2,
+			ThrottledSeconds:      1,
+			MaxRSSBytes:           100,
+			MaxGoroutines:         10,
+			NumGC:                 5,
+			GCPauseSeconds:        0.25,
+			MaxGCPauseSeconds:     0.01,
+			PercentileLimiterLags: [][2]float64{{0.5, 0.1}, {0.99, 0.5}},
+		},
+		nil,
+		&types.RunnerSelfStats{
+			CPUSeconds:            20,
+			AvgCPUCores:           1.8,
+			MaxCPUCores:           2,
+			AvailableCPUCores:     4,
+			MaxRSSBytes:           300,
+			MaxGoroutines:         5,
+			NumGC:                 7,
+			GCPauseSeconds:        0.5,
+			MaxGCPauseSeconds:     0.005,
+			PercentileLimiterLags: [][2]float64{{0.5, 0.2}, {0.99, 0.3}},
+		},
+	)
+	assert.Equal(t, &types.RunnerSelfStats{
+		CPUSeconds:            30,
+		AvgCPUCores:           1.8,
+		MaxCPUCores:           2,
+		AvailableCPUCores:     2,
+		ThrottledSeconds:      1,
+		MaxRSSBytes:           300,
+		MaxGoroutines:         10,
+		NumGC:                 12,
+		GCPauseSeconds:        0.75,
+		MaxGCPauseSeconds:     0.01,
+		PercentileLimiterLags: [][2]float64{{0.5, 0.2}, {0.99, 0.5}},
+	}, merged)
+}
@@ -0,0 +1,156 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/kperf/api/types"
+)
+
+// EvaluateSLO checks report against SLO's thresholds.
+func EvaluateSLO(slo *types.SLO, report *types.RunnerMetricReport) *types.SLOVerdict {
+	verdict := &types.SLOVerdict{Passed: true}
+
+	add := func(check types.SLOCheck) {
+		if !check.Passed {
+			verdict.Passed = false
+		}
+		verdict.Checks = append(verdict.Checks, check)
+	}
+
+	for _, l := range slo.P99Latencies {
+		for _, check := range evaluateP99Latency(l, report) {
+			add(check)
+		}
+	}
+
+	total := report.Total
+	failed := 0
+	for _, n := range report.ErrorStats {
+		failed += int(n)
+	}
+	tooManyRequests := 0
+	for _, typ := range []types.ResponseErrorType{
+		types.ResponseErrorTypeHTTP,
+		types.ResponseErrorTypeAPFRejected,
+		types.ResponseErrorTypeMaxInflightRejected,
+	} {
+		tooManyRequests += int(report.ErrorStats[fmt.Sprintf("%s/%d", typ, http.StatusTooManyRequests)])
+	}
+
+	if r := slo.MaxErrorRatio; r != nil {
+		actual := ratio(failed, total)
+		add(types.SLOCheck{
+			Name:      "errorRatio",
+			Threshold: *r,
+			Actual:    actual,
+			Passed:    actual <= *r,
+		})
+	}
+
+	if r := slo.MaxTooManyRequestsRatio; r != nil {
+		actual := ratio(tooManyRequests, total)
+		add(types.SLOCheck{
+			Name:      "tooManyRequestsRatio",
+			Threshold: *r,
+			Actual:    actual,
+			Passed:    actual <= *r,
+		})
+	}
+
+	if slo.MinQPS > 0 {
+		check := types.SLOCheck{
+			Name:      "qps",
+			Threshold: slo.MinQPS,
+		}
+
+		dur, err := time.ParseDuration(report.Duration)
+		if err != nil || dur <= 0 {
+			check.Message = fmt.Sprintf("invalid duration %q", report.Duration)
+		} else {
+			check.Actual = float64(total) / dur.Seconds()
+			check.Passed = check.Actual >= slo.MinQPS
+		}
+		add(check)
+	}
+	return verdict
+}
+
+// evaluateP99Latency checks p99 latency for the requests matched by URL or
+// tag. If there are multiple matched URLs, each URL should meet the threshold.
+func evaluateP99Latency(l types.LatencyThreshold, report *types.RunnerMetricReport) []types.SLOCheck {
+	threshold := l.Max.Seconds()
+
+	if l.URL == "" {
+		name, latencies := "p99Latency", report.PercentileLatencies
+		if l.Tag != "" {
+			name = fmt.Sprintf("p99Latency[tag:%s]", l.Tag)
+			latencies = nil
+			if s, ok := report.StatsByTag[l.Tag]; ok {
+				latencies = s.PercentileLatencies
+			}
+		}
+
+		check := types.SLOCheck{
+			Name:      name,
+			Threshold: threshold,
+		}
+
+		p99, ok := PercentileValue(latencies, 0.99)
+		if !ok {
+			check.Message = "no latency data"
+			return []types.SLOCheck{check}
+		}
+		check.Actual = p99
+		check.Passed = p99 <= threshold
+		return []types.SLOCheck{check}
+	}
+
+	urls := make([]string, 0, len(report.PercentileLatenciesByURL))
+	for u := range report.PercentileLatenciesByURL {
+		if strings.Contains(u, l.URL) {
+			urls = append(urls, u)
+		}
+	}
+	sort.Strings(urls)
+
+	if len(urls) == 0 {
+		msg := "no matched requests"
+		if len(report.PercentileLatenciesByURL) == 0 {
+			msg = "no latencies by URL in report"
+		}
+		return []types.SLOCheck{
+			{
+				Name:      fmt.Sprintf("p99Latency[%s]", l.URL),
+				Threshold: threshold,
+				Message:   msg,
+			},
+		}
+	}
+
+	res := make([]types.SLOCheck, 0, len(urls))
+	for _, u := range urls {
+		p99, _ := PercentileValue(report.PercentileLatenciesByURL[u], 0.99)
+		res = append(res, types.SLOCheck{
+			Name:      fmt.Sprintf("p99Latency[%s]", u),
+			Threshold: threshold,
+			Actual:    p99,
+			Passed:    p99 <= threshold,
+		})
+	}
+	return res
+}
+
+// ratio returns n / total. It returns zero if total is zero.
+func ratio(n, total int) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
@@ -0,0 +1,70 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Azure/kperf/api/types"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluateSLO(t *testing.T) {
+	maxErrorRatio := 0.01
+	maxTooManyRequestsRatio := 0.05
+
+	report := &types.RunnerMetricReport{
+		Total:    100,
+		Duration: "10s",
+		ErrorStats: map[string]int32{
+			"http/429": 2,
+		},
+		PercentileLatencies: [][2]float64{{0.99, 0.5}},
+		PercentileLatenciesByURL: map[string][][2]float64{
+			"https://127.0.0.1/api/v1/pods":  {{0.99, 0.3}},
+			"https://127.0.0.1/api/v1/nodes": {{0.99, 2}},
+		},
+		StatsByTag: map[string]*types.RequestStats{
+			"list-pods": {PercentileLatencies: [][2]float64{{0.99, 0.3}}},
+		},
+	}
+
+	verdict := EvaluateSLO(&types.SLO{
+		P99Latencies: []types.LatencyThreshold{
+			{Max: time.Second},
+			{URL: "/pods", Max: time.Second},
+			{Tag: "list-pods", Max: time.Second},
+		},
+		MaxTooManyRequestsRatio: &maxTooManyRequestsRatio,
+		MinQPS:                  10,
+	}, report)
+	assert.True(t, verdict.Passed)
+	assert.Len(t, verdict.Checks, 5)
+
+	verdict = EvaluateSLO(&types.SLO{
+		P99Latencies: []types.LatencyThreshold{
+			{URL: "/api/v1", Max: time.Second},
+			{URL: "/secrets", Max: time.Second},
+			{Tag: "get-secrets", Max: time.Second},
+		},
+		MaxErrorRatio: &maxErrorRatio,
+		MinQPS:        20,
+	}, report)
+	assert.False(t, verdict.Passed)
+
+	passed := map[string]bool{}
+	for _, c := range verdict.Checks {
+		passed[c.Name] = c.Passed
+	}
+	assert.Equal(t, map[string]bool{
+		"p99Latency[https://127.0.0.1/api/v1/nodes]": false,
+		"p99Latency[https://127.0.0.1/api/v1/pods]":  true,
+		"p99Latency[/secrets]":                       false,
+		"p99Latency[tag:get-secrets]":                false,
+		"errorRatio":                                 false,
+		"qps":                                        false,
+	}, passed)
+}
@@ -0,0 +1,52 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Azure/kperf/api/types"
+)
+
+// Snapshotter gathers ResponseMetric's statistics before benchmark finishes,
+// for instance, to upload interim report to runner group server.
+type Snapshotter struct {
+	mu sync.Mutex
+	m  ResponseMetric
+	// start is the time when ResponseMetric was created.
+	start time.Time
+}
+
+// NewSnapshotter returns Snapshotter which should be passed to ResponseMetric
+// by WithSnapshotterOpt.
+func NewSnapshotter() *Snapshotter {
+	return &Snapshotter{}
+}
+
+// WithSnapshotterOpt allows Snapshotter to gather statistics from
+// ResponseMetric.
+func WithSnapshotterOpt(s *Snapshotter) ResponseMetricOpt {
+	return func(m *responseMetricImpl) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.m = m
+		s.start = time.Now()
+	}
+}
+
+// Snapshot returns the statistics gathered so far and the elapsed time since
+// ResponseMetric was created. It returns false if ResponseMetric hasn't been
+// created.
+func (s *Snapshotter) Snapshot() (types.ResponseStats, time.Duration, bool) {
+	s.mu.Lock()
+	m, start := s.m, s.start
+	s.mu.Unlock()
+
+	if m == nil {
+		return types.ResponseStats{}, 0, false
+	}
+	return m.Gather(), time.Since(start), true
+}
@@ -0,0 +1,39 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotter(t *testing.T) {
+	s := NewSnapshotter()
+
+	// not created yet
+	_, _, ok := s.Snapshot()
+	assert.False(t, ok)
+
+	m := NewResponseMetric(WithSnapshotterOpt(s))
+
+	req := RequestInfo{Verb: "LIST", Resource: "pods", Scope: "cluster", Tag: "list-pods"}
+	m.ObserveDispatch(req, 0)
+	m.ObserveLatency(req, 0.1)
+
+	stats, elapsed, ok := s.Snapshot()
+	require.True(t, ok)
+	assert.GreaterOrEqual(t, elapsed.Nanoseconds(), int64(0))
+	require.Contains(t, stats.StatsByTag, "list-pods")
+	assert.Equal(t, 1, stats.StatsByTag["list-pods"].Total)
+
+	// the later snapshot includes new observations
+	m.ObserveDispatch(req, 0)
+	m.ObserveLatency(req, 0.1)
+
+	stats, _, ok = s.Snapshot()
+	require.True(t, ok)
+	assert.Equal(t, 2, stats.StatsByTag["list-pods"].Total)
+}
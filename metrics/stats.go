@@ -0,0 +1,75 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package metrics
+
+import (
+	"github.com/Azure/kperf/api/types"
+)
+
+// requestStats aggregates statistics of a group of requests.
+//
+// NOTE: It's not thread-safe.
+type requestStats struct {
+	info       RequestInfo
+	total      int
+	errorStats map[string]int32
+	latencies  *Histogram
+}
+
+// newRequestStats returns empty requestStats. Only verb, group, resource and
+// scope in info are reported.
+func newRequestStats(info RequestInfo) *requestStats {
+	return &requestStats{
+		info:       info,
+		errorStats: map[string]int32{},
+		latencies:  NewHistogram(),
+	}
+}
+
+// snapshot returns serialized statistics.
+func (s *requestStats) snapshot() *types.RequestStats {
+	res := &types.RequestStats{
+		Verb:     s.info.Verb,
+		Group:    s.info.Group,
+		Resource: s.info.Resource,
+		Scope:    s.info.Scope,
+		Total:    s.total,
+	}
+	if len(s.errorStats) > 0 {
+		res.ErrorStats = make(map[string]int32, len(s.errorStats))
+		mergeErrorStats(res.ErrorStats, s.errorStats)
+	}
+	if s.latencies.Count() > 0 {
+		res.Histogram = s.latencies.Snapshot()
+	}
+	return res
+}
+
+// MergeRequestStats merges statistics from src into dst. The histogram in
+// src is copied so that dst doesn't share memory with src.
+func MergeRequestStats(dst, src map[string]*types.RequestStats) {
+	for key, s := range src {
+		d, ok := dst[key]
+		if !ok {
+			d = &types.RequestStats{
+				Verb:     s.Verb,
+				Group:    s.Group,
+				Resource: s.Resource,
+				Scope:    s.Scope,
+			}
+			dst[key] = d
+		}
+
+		d.Total += s.Total
+		if len(s.ErrorStats) > 0 {
+			if d.ErrorStats == nil {
+				d.ErrorStats = map[string]int32{}
+			}
+			mergeErrorStats(d.ErrorStats, s.ErrorStats)
+		}
+		if s.Histogram != nil {
+			d.Histogram = MergeHistograms(d.Histogram, s.Histogram).Snapshot()
+		}
+	}
+}
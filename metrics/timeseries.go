@@ -0,0 +1,130 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package metrics
+
+import (
+	"sort"
+	"time"
+
+	"github.com/Azure/kperf/api/types"
+)
+
+// timeSeries groups statistics by wall clock interval so that the buckets
+// from different runners can be merged by timestamp.
+//
+// NOTE: It's not thread-safe.
+type timeSeries struct {
+	interval time.Duration
+	buckets  map[int64]*timeSeriesBucket
+}
+
+type timeSeriesBucket struct {
+	total         int
+	receivedBytes int64
+	errorStats    map[string]int32
+	latencies     *Histogram
+}
+
+func newTimeSeries(interval time.Duration) *timeSeries {
+	return &timeSeries{
+		interval: interval,
+		buckets:  map[int64]*timeSeriesBucket{},
+	}
+}
+
+// bucket returns the bucket which contains the given time.
+func (ts *timeSeries) bucket(now time.Time) *timeSeriesBucket {
+	key := now.Truncate(ts.interval).UnixNano()
+
+	b, ok := ts.buckets[key]
+	if !ok {
+		b = &timeSeriesBucket{
+			errorStats: map[string]int32{},
+			latencies:  NewHistogram(),
+		}
+		ts.buckets[key] = b
+	}
+	return b
+}
+
+// dump returns buckets sorted by timestamp.
+func (ts *timeSeries) dump() []types.TimeSeriesBucket {
+	res := make([]types.TimeSeriesBucket, 0, len(ts.buckets))
+	for key, b := range ts.buckets {
+		bucket := types.TimeSeriesBucket{
+			Timestamp:     time.Unix(0, key).UTC(),
+			Total:         b.total,
+			ReceivedBytes: b.receivedBytes,
+		}
+		if len(b.errorStats) > 0 {
+			bucket.ErrorStats = make(map[string]int32, len(b.errorStats))
+			mergeErrorStats(bucket.ErrorStats, b.errorStats)
+		}
+		if b.latencies.Count() > 0 {
+			bucket.Histogram = b.latencies.Snapshot()
+		}
+		res = append(res, bucket)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Timestamp.Before(res[j].Timestamp)
+	})
+	return res
+}
+
+// MergeTimeSeries merges buckets with the same timestamp and returns buckets
+// sorted by timestamp. The histograms are merged as well.
+func MergeTimeSeries(series ...[]types.TimeSeriesBucket) []types.TimeSeriesBucket {
+	type mergedBucket struct {
+		bucket    types.TimeSeriesBucket
+		latencies *Histogram
+	}
+
+	merged := map[int64]*mergedBucket{}
+	for _, buckets := range series {
+		for _, b := range buckets {
+			key := b.Timestamp.UnixNano()
+
+			m, ok := merged[key]
+			if !ok {
+				m = &mergedBucket{
+					bucket: types.TimeSeriesBucket{
+						Timestamp:  b.Timestamp.UTC(),
+						ErrorStats: map[string]int32{},
+					},
+					latencies: NewHistogram(),
+				}
+				merged[key] = m
+			}
+
+			m.bucket.Total += b.Total
+			m.bucket.ReceivedBytes += b.ReceivedBytes
+			mergeErrorStats(m.bucket.ErrorStats, b.ErrorStats)
+			m.latencies.Merge(b.Histogram)
+		}
+	}
+
+	res := make([]types.TimeSeriesBucket, 0, len(merged))
+	for _, m := range merged {
+		if len(m.bucket.ErrorStats) == 0 {
+			m.bucket.ErrorStats = nil
+		}
+		if m.latencies.Count() > 0 {
+			m.bucket.Histogram = m.latencies.Snapshot()
+		}
+		res = append(res, m.bucket)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Timestamp.Before(res[j].Timestamp)
+	})
+	return res
+}
+
+// mergeErrorStats merges error stats from src into dst.
+func mergeErrorStats(dst, src map[string]int32) {
+	for e, n := range src {
+		dst[e] += n
+	}
+}
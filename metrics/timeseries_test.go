@@ -0,0 +1,73 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Azure/kperf/api/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+func TestResponseMetric_TimeSeries(t *testing.T) {
+	m := NewResponseMetric()
+	m.ObserveLatency(RequestInfo{URL: "a"}, 0.1)
+	assert.Nil(t, m.Gather().TimeSeries)
+
+	m = NewResponseMetric(WithTimeSeriesIntervalOpt(time.Hour))
+	m.ObserveLatency(RequestInfo{URL: "a"}, 0.1)
+	m.ObserveLatency(RequestInfo{URL: "b"}, 0.2)
+	m.ObserveReceivedBytes(10)
+	m.ObserveFailure(RequestInfo{URL: "a"}, time.Now(), 0.3, apierrors.NewTooManyRequestsError("retry it later"))
+	m.ObserveFailure(RequestInfo{URL: "a"}, time.Now().Add(-2*time.Hour), 0.3, apierrors.NewTooManyRequestsError("retry it later"))
+
+	ts := m.Gather().TimeSeries
+	require.Len(t, ts, 2)
+	assert.True(t, ts[0].Timestamp.Before(ts[1].Timestamp))
+
+	assert.Equal(t, 1, ts[0].Total)
+	assert.Nil(t, ts[0].Histogram)
+	assert.Equal(t, map[string]int32{"http/429": 1}, ts[0].ErrorStats)
+
+	assert.Equal(t, 3, ts[1].Total)
+	assert.Equal(t, int64(10), ts[1].ReceivedBytes)
+	assert.Equal(t, map[string]int32{"http/429": 1}, ts[1].ErrorStats)
+	require.NotNil(t, ts[1].Histogram)
+	assert.Equal(t, int64(2), ts[1].Histogram.Count)
+}
+
+func TestMergeTimeSeries(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	t1 := t0.Add(time.Second)
+
+	h := NewHistogram()
+	h.Observe(0.1)
+
+	a := []types.TimeSeriesBucket{
+		{Timestamp: t0, Total: 1, ReceivedBytes: 10, Histogram: h.Snapshot()},
+		{Timestamp: t1, Total: 2, ErrorStats: map[string]int32{"http/429": 2}},
+	}
+	b := []types.TimeSeriesBucket{
+		{Timestamp: t1.In(time.Local), Total: 1, ReceivedBytes: 5, Histogram: h.Snapshot()},
+	}
+
+	res := MergeTimeSeries(MergeTimeSeries(nil, a), b)
+	require.Len(t, res, 2)
+
+	assert.Equal(t, t0, res[0].Timestamp)
+	assert.Equal(t, 1, res[0].Total)
+	assert.Equal(t, int64(10), res[0].ReceivedBytes)
+	assert.Nil(t, res[0].ErrorStats)
+	assert.Equal(t, int64(1), res[0].Histogram.Count)
+
+	assert.Equal(t, t1, res[1].Timestamp)
+	assert.Equal(t, 3, res[1].Total)
+	assert.Equal(t, int64(5), res[1].ReceivedBytes)
+	assert.Equal(t, map[string]int32{"http/429": 2}, res[1].ErrorStats)
+	assert.Equal(t, int64(1), res[1].Histogram.Count)
+}
@@ -4,6 +4,9 @@
 package metrics
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"io"
@@ -17,16 +20,18 @@ import (
 	"github.com/Azure/kperf/api/types"
 	"golang.org/x/net/http2"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// percentiles is the list of percentiles in report.
+var percentiles = []float64{0, 0.5, 0.90, 0.95, 0.99, 1}
+
 // BuildPercentileLatencies builds percentile latencies.
 func BuildPercentileLatencies(latencies []float64) [][2]float64 {
 	if len(latencies) == 0 {
 		return nil
 	}
 
-	var percentiles = []float64{0, 0.5, 0.90, 0.95, 0.99, 1}
-
 	res := make([][2]float64, len(percentiles))
 
 	n := len(latencies)
@@ -41,31 +46,167 @@ func BuildPercentileLatencies(latencies []float64) [][2]float64 {
 	return res
 }
 
+// PercentileValue returns the value of the percentile from the result of
+// BuildPercentileLatencies.
+func PercentileValue(percentiles [][2]float64, p float64) (float64, bool) {
+	for _, v := range percentiles {
+		if v[0] == p {
+			return v[1], true
+		}
+	}
+	return 0, false
+}
+
 // BuildErrorStatsGroupByType summaries total count for each type of errors.
 func BuildErrorStatsGroupByType(errors []types.ResponseError) map[string]int32 {
 	res := map[string]int32{}
 
 	for _, err := range errors {
-		var key string
-		switch err.Type {
-		case types.ResponseErrorTypeHTTP:
-			key = fmt.Sprintf("%s/%d", err.Type, err.Code)
-		default:
-			key = fmt.Sprintf("%s/%s", err.Type, err.Message)
-		}
-		res[key]++
+		res[errorStatKey(err)]++
 	}
 	return res
 }
 
+// errorStatKey returns the key of error in error stats.
+func errorStatKey(err types.ResponseError) string {
+	if err.Code != 0 {
+		return fmt.Sprintf("%s/%d", err.Type, err.Code)
+	}
+	return fmt.Sprintf("%s/%s", err.Type, err.Message)
+}
+
+// MergePriorityLevelStats merges statistics from src into dst. The histogram
+// in src is copied so that dst doesn't share memory with src.
+func MergePriorityLevelStats(dst, src map[string]*types.PriorityLevelStats) {
+	for name, s := range src {
+		d, ok := dst[name]
+		if !ok {
+			d = &types.PriorityLevelStats{
+				FlowSchemas: map[string]int{},
+			}
+			dst[name] = d
+		}
+
+		d.Total += s.Total
+		d.TooManyRequests += s.TooManyRequests
+		d.Retries += s.Retries
+		for fs, n := range s.FlowSchemas {
+			d.FlowSchemas[fs] += n
+		}
+		if s.Histogram != nil {
+			d.Histogram = MergeHistograms(d.Histogram, s.Histogram).Snapshot()
+		}
+	}
+}
+
 var (
 	// errHTTP2ClientConnectionLost is used to track unexported http2 error.
 	errHTTP2ClientConnectionLost = errors.New("http2: client connection lost")
 
 	// errTLSHandshakeTimeout is used to track unexported tlsHandshakeTimeoutError from net/http.
 	errTLSHandshakeTimeout = errors.New("net/http: TLS handshake timeout")
+
+	// errClientTimeout is used to track unexported error when http.Client's
+	// Timeout is exceeded.
+	errClientTimeout = errors.New("Client.Timeout exceeded")
 )
 
+// tooManyRequestsMessage is the response body when kube-apiserver's
+// APIPriorityAndFairness or max-inflight filter rejects the request.
+const tooManyRequestsMessage = "Too many requests, please try again later."
+
+// isFilterRejection returns true if the error is 429 response returned by
+// kube-apiserver's APIPriorityAndFairness or max-inflight filter.
+//
+// NOTE: Both filters respond with plain text, instead of Status object. So
+// client-go puts the body into Status's details as an unexpected response.
+func isFilterRejection(err error) bool {
+	status, ok := err.(apierrors.APIStatus)
+	if !ok && !errors.As(err, &status) {
+		return false
+	}
+
+	s := status.Status()
+	if s.Code != http.StatusTooManyRequests || s.Details == nil {
+		return false
+	}
+	for _, cause := range s.Details.Causes {
+		if cause.Type == metav1.CauseTypeUnexpectedServerResponse &&
+			strings.Contains(cause.Message, tooManyRequestsMessage) {
+			return true
+		}
+	}
+	return false
+}
+
+// isContextCanceled returns true if the request was canceled by client.
+func isContextCanceled(err error) bool {
+	return errors.Is(err, context.Canceled)
+}
+
+// isClientTimeout returns true if the request didn't complete before
+// client-side timeout.
+func isClientTimeout(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) ||
+		strings.Contains(err.Error(), errClientTimeout.Error())
+}
+
+// isHTTP2RefusedStream returns true if server refused the stream.
+func isHTTP2RefusedStream(err error) bool {
+	if streamErr, ok := err.(http2.StreamError); ok || errors.As(err, &streamErr) {
+		return streamErr.Code == http2.ErrCodeRefusedStream
+	}
+	return false
+}
+
+// isDNSError returns true if client failed to resolve host.
+func isDNSError(err error) (string, bool) {
+	var dnsErr *net.DNSError
+	if !errors.As(err, &dnsErr) {
+		return "", false
+	}
+
+	switch {
+	case dnsErr.IsNotFound:
+		return "no such host", true
+	case dnsErr.IsTimeout:
+		return "timeout", true
+	default:
+		return dnsErr.Err, true
+	}
+}
+
+// isTLSError returns true if it's related to TLS handshake or certificate.
+// The message doesn't include certificate details so that it can be
+// compared across runs.
+func isTLSError(err error) (string, bool) {
+	var (
+		unknownAuthorityErr x509.UnknownAuthorityError
+		invalidCertErr      x509.CertificateInvalidError
+		hostnameErr         x509.HostnameError
+		verificationErr     *tls.CertificateVerificationError
+		recordHeaderErr     tls.RecordHeaderError
+		alertErr            tls.AlertError
+	)
+
+	switch {
+	case errors.As(err, &unknownAuthorityErr):
+		return "x509: certificate signed by unknown authority", true
+	case errors.As(err, &invalidCertErr):
+		return "x509: invalid certificate", true
+	case errors.As(err, &hostnameErr):
+		return "x509: certificate is not valid for host", true
+	case errors.As(err, &verificationErr):
+		return "tls: failed to verify certificate", true
+	case errors.As(err, &recordHeaderErr):
+		return "tls: first record does not look like a TLS handshake", true
+	case errors.As(err, &alertErr):
+		return "tls: " + alertErr.Error(), true
+	default:
+		return "", false
+	}
+}
+
 // codeFromHTTP parses error to get http code.
 func codeFromHTTP(err error) int {
 	if err == nil {
@@ -0,0 +1,151 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package request
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/kperf/api/types"
+	"github.com/Azure/kperf/metrics"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog/v2"
+	"k8s.io/kubectl/pkg/scheme"
+)
+
+// APIServerMetricsScraper scrapes /metrics from each kube-apiserver replica.
+type APIServerMetricsScraper struct {
+	// clients stores REST client for each replica, keyed by address.
+	clients map[string]rest.Interface
+}
+
+// NewAPIServerMetricsScraper resolves kube-apiserver's host into IP addresses
+// and creates one REST client for each address. All the clients still use
+// kube-apiserver's host name for TLS verification.
+func NewAPIServerMetricsScraper(kubeCfgPath string, userAgent string) (*APIServerMetricsScraper, error) {
+	restCfg, err := clientcmd.BuildConfigFromFlags("", kubeCfgPath)
+	if err != nil {
+		return nil, err
+	}
+	restCfg.NegotiatedSerializer = scheme.Codecs.WithoutConversion()
+	if userAgent != "" {
+		restCfg.UserAgent = userAgent
+	}
+
+	addrs, err := resolveAPIServerAddrs(restCfg.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	clients := make(map[string]rest.Interface, len(addrs))
+	for _, addr := range addrs {
+		cfg := rest.CopyConfig(restCfg)
+
+		target := addr
+		dialer := &net.Dialer{Timeout: 30 * time.Second}
+		cfg.Dial = func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, target)
+		}
+
+		cli, err := rest.UnversionedRESTClientFor(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create client for %s: %w", addr, err)
+		}
+		clients[addr] = cli
+	}
+	return &APIServerMetricsScraper{clients: clients}, nil
+}
+
+// Scrape returns snapshots for all the reachable replicas.
+func (s *APIServerMetricsScraper) Scrape(ctx context.Context) map[string]*metrics.APIServerMetricsSnapshot {
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		res = make(map[string]*metrics.APIServerMetricsSnapshot, len(s.clients))
+	)
+
+	for addr, cli := range s.clients {
+		wg.Add(1)
+		go func(addr string, cli rest.Interface) {
+			defer wg.Done()
+
+			data, err := cli.Get().AbsPath("/metrics").Timeout(defaultTimeout).DoRaw(ctx)
+			if err != nil {
+				klog.V(2).ErrorS(err, "failed to scrape kube-apiserver's metrics", "addr", addr)
+				return
+			}
+
+			snapshot, err := metrics.ParseAPIServerMetrics(bytes.NewReader(data), time.Now())
+			if err != nil {
+				klog.V(2).ErrorS(err, "failed to parse kube-apiserver's metrics", "addr", addr)
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			res[addr] = snapshot
+		}(addr, cli)
+	}
+	wg.Wait()
+	return res
+}
+
+// DiffAPIServerMetrics returns the change for the replicas which have both
+// snapshots.
+func DiffAPIServerMetrics(before, after map[string]*metrics.APIServerMetricsSnapshot) map[string]*types.APIServerMetricsDelta {
+	res := make(map[string]*types.APIServerMetricsDelta, len(after))
+	for addr, a := range after {
+		b, ok := before[addr]
+		if !ok {
+			continue
+		}
+		res[addr] = metrics.DiffAPIServerMetrics(b, a)
+	}
+	return res
+}
+
+// resolveAPIServerAddrs returns the list of host:port for kube-apiserver's
+// replicas behind the host.
+func resolveAPIServerAddrs(host string) ([]string, error) {
+	if !strings.Contains(host, "://") {
+		host = "https://" + host
+	}
+
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse host %s: %w", host, err)
+	}
+
+	hostname, port := u.Hostname(), u.Port()
+	if port == "" {
+		port = "443"
+		if u.Scheme == "http" {
+			port = "80"
+		}
+	}
+
+	ips := []string{hostname}
+	if net.ParseIP(hostname) == nil {
+		ips, err = net.LookupHost(hostname)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %w", hostname, err)
+		}
+	}
+	sort.Strings(ips)
+
+	addrs := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		addrs = append(addrs, net.JoinHostPort(ip, port))
+	}
+	return addrs, nil
+}
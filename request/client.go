@@ -4,14 +4,24 @@
 package request
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"math"
+	"math/rand/v2"
+	"net"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/Azure/kperf/api/types"
 
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	apitypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/klog/v2"
 	"k8s.io/kubectl/pkg/scheme"
 )
 
@@ -43,6 +53,12 @@ func NewClients(kubeCfgPath string, connsNum int, opts ...ClientCfgOpt) ([]rest.
 	// REF: https://github.com/kubernetes/client-go/blob/c5938c6876a62f53c1f4ee55b879ca5c74253ae8/transport/cache.go#L154
 	restCfg.Proxy = http.ProxyFromEnvironment
 
+	// Track kube-apiserver's flowcontrol response headers and retries.
+	restCfg.Wrap(newResponseTrackerRoundTripper)
+
+	// Propagate trace context so that kube-apiserver's spans join ours.
+	restCfg.Wrap(newTraceContextRoundTripper)
+
 	err = cfg.apply(restCfg)
 	if err != nil {
 		return nil, err
@@ -50,6 +66,15 @@ func NewClients(kubeCfgPath string, connsNum int, opts ...ClientCfgOpt) ([]rest.
 
 	restClients := make([]rest.Interface, 0, connsNum)
 	for i := 0; i < connsNum; i++ {
+		if cfg.connChurn != nil {
+			restCli, err := newChurnClient(restCfg, *cfg.connChurn)
+			if err != nil {
+				return nil, err
+			}
+			restClients = append(restClients, restCli)
+			continue
+		}
+
 		cfgShallowCopy := *restCfg
 
 		restCli, err := rest.UnversionedRESTClientFor(&cfgShallowCopy)
@@ -61,6 +86,287 @@ func NewClients(kubeCfgPath string, connsNum int, opts ...ClientCfgOpt) ([]rest.
 	return restClients, nil
 }
 
+// Reconnector is the client which is able to drop current connection and
+// rebuild new one.
+type Reconnector interface {
+	Reconnect()
+}
+
+// churnClient implements rest.Interface. It rebuilds underlying REST client
+// based on ConnChurn setting. Since transport is uncacheable, rebuilding
+// REST client means new connection, which requires TLS handshake and
+// authentication again.
+type churnClient struct {
+	mu sync.Mutex
+
+	restCfg *rest.Config
+	churn   types.ConnChurn
+
+	cli *rest.RESTClient
+	// conns tracks connections and in-flight requests of current cli.
+	conns *churnConns
+	// requests is the number of requests sent by current cli.
+	requests int
+	// deadline is the time to rebuild cli if Interval is set.
+	deadline time.Time
+}
+
+var _ rest.Interface = &churnClient{}
+
+func newChurnClient(restCfg *rest.Config, churn types.ConnChurn) (*churnClient, error) {
+	c := &churnClient{
+		restCfg: restCfg,
+		churn:   churn,
+	}
+	if err := c.rebuildLocked(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Reconnect implements Reconnector.
+func (c *churnClient) Reconnect() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.rebuildLocked(); err != nil {
+		klog.V(2).ErrorS(err, "failed to rebuild client for reconnect")
+	}
+}
+
+// current returns REST client for next request and rebuilds it if it has
+// reached the limit of requests or interval.
+func (c *churnClient) current() *rest.RESTClient {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expired := (c.churn.EveryRequests > 0 && c.requests >= c.churn.EveryRequests) ||
+		(!c.deadline.IsZero() && time.Now().After(c.deadline))
+	if expired {
+		if err := c.rebuildLocked(); err != nil {
+			klog.V(2).ErrorS(err, "failed to rebuild client for connection churn")
+		}
+	}
+
+	c.requests++
+	return c.cli
+}
+
+// rebuildLocked replaces current REST client with new one.
+func (c *churnClient) rebuildLocked() error {
+	conns := newChurnConns(c.restCfg.Dial)
+
+	cfgShallowCopy := *c.restCfg
+	cfgShallowCopy.Dial = conns.dial
+	cfgShallowCopy.Wrap(conns.wrapRoundTripper)
+	// NOTE: Keep the rate limiter so that rebuilding doesn't reset QPS.
+	if old := c.cli; old != nil {
+		cfgShallowCopy.RateLimiter = old.GetRateLimiter()
+	}
+
+	cli, err := rest.UnversionedRESTClientFor(&cfgShallowCopy)
+	if err != nil {
+		return err
+	}
+
+	// NOTE: The in-flight requests still hold the old connections. They
+	// will be closed after those requests finish.
+	if old := c.conns; old != nil {
+		old.retire()
+	}
+
+	c.cli = cli
+	c.conns = conns
+	c.requests = 0
+	c.deadline = time.Time{}
+	if c.churn.Interval > 0 {
+		c.deadline = time.Now().Add(c.churn.Interval + randomDuration(c.churn.Jitter))
+	}
+	return nil
+}
+
+// churnConns tracks connections and in-flight requests of one REST client
+// so that the connections can be closed once the client has been replaced
+// and all its requests have finished.
+type churnConns struct {
+	mu       sync.Mutex
+	conns    map[net.Conn]struct{}
+	inflight int
+	retired  bool
+
+	dialFn func(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+// newChurnConns returns churnConns which creates connections by dialFn. If
+// dialFn is nil, it uses net.Dialer.
+func newChurnConns(dialFn func(ctx context.Context, network, addr string) (net.Conn, error)) *churnConns {
+	if dialFn == nil {
+		dialer := &net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}
+		dialFn = dialer.DialContext
+	}
+
+	return &churnConns{
+		conns:  map[net.Conn]struct{}{},
+		dialFn: dialFn,
+	}
+}
+
+// dial creates and records new connection.
+func (cc *churnConns) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	conn, err := cc.dialFn(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	tc := &churnConn{Conn: conn, owner: cc}
+	cc.conns[tc] = struct{}{}
+	return tc, nil
+}
+
+// wrapRoundTripper counts in-flight requests. A request finishes when its
+// response's body is closed.
+func (cc *churnConns) wrapRoundTripper(rt http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		cc.mu.Lock()
+		cc.inflight++
+		cc.mu.Unlock()
+
+		resp, err := rt.RoundTrip(req)
+		if err != nil {
+			cc.requestDone()
+			return nil, err
+		}
+		resp.Body = &churnBody{ReadCloser: resp.Body, done: cc.requestDone}
+		return resp, nil
+	})
+}
+
+// retire marks REST client replaced. The connections are closed at once if
+// there is no in-flight request.
+func (cc *churnConns) retire() {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	cc.retired = true
+	if cc.inflight == 0 {
+		cc.closeAllLocked()
+	}
+}
+
+func (cc *churnConns) requestDone() {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	cc.inflight--
+	if cc.retired && cc.inflight == 0 {
+		cc.closeAllLocked()
+	}
+}
+
+func (cc *churnConns) closeAllLocked() {
+	for conn := range cc.conns {
+		if tc, ok := conn.(*churnConn); ok {
+			_ = tc.Conn.Close()
+		}
+		delete(cc.conns, conn)
+	}
+}
+
+// churnConn removes itself from owner after close.
+type churnConn struct {
+	net.Conn
+	owner *churnConns
+}
+
+func (c *churnConn) Close() error {
+	c.owner.mu.Lock()
+	delete(c.owner.conns, c)
+	c.owner.mu.Unlock()
+
+	return c.Conn.Close()
+}
+
+// churnBody calls done once after close.
+type churnBody struct {
+	io.ReadCloser
+	once sync.Once
+	done func()
+}
+
+func (b *churnBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.done)
+	return err
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// GetRateLimiter implements rest.Interface.
+func (c *churnClient) GetRateLimiter() flowcontrol.RateLimiter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.cli.GetRateLimiter()
+}
+
+// Verb implements rest.Interface.
+func (c *churnClient) Verb(verb string) *rest.Request {
+	return c.current().Verb(verb)
+}
+
+// Post implements rest.Interface.
+func (c *churnClient) Post() *rest.Request {
+	return c.current().Post()
+}
+
+// Put implements rest.Interface.
+func (c *churnClient) Put() *rest.Request {
+	return c.current().Put()
+}
+
+// Patch implements rest.Interface.
+func (c *churnClient) Patch(pt apitypes.PatchType) *rest.Request {
+	return c.current().Patch(pt)
+}
+
+// Get implements rest.Interface.
+func (c *churnClient) Get() *rest.Request {
+	return c.current().Get()
+}
+
+// Delete implements rest.Interface.
+func (c *churnClient) Delete() *rest.Request {
+	return c.current().Delete()
+}
+
+// APIVersion implements rest.Interface.
+func (c *churnClient) APIVersion() schema.GroupVersion {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.cli.APIVersion()
+}
+
+// randomDuration returns random duration in [0, upper).
+func randomDuration(upper time.Duration) time.Duration {
+	if upper <= 0 {
+		return 0
+	}
+
+	// NOTE: Jitter doesn't need crypto randomness.
+	return time.Duration(rand.Int64N(int64(upper)))
+}
+
 // defaultClientCfg is default setting for http client.
 var defaultClientCfg = clientCfg{
 	qps:         float64(math.MaxInt32),
@@ -72,6 +378,7 @@ type clientCfg struct {
 	qps          float64
 	contentType  types.ContentType
 	disableHTTP2 bool
+	connChurn    *types.ConnChurn
 }
 
 // apply sets value to k8s.io/client-go/rest.Config.
@@ -134,3 +441,10 @@ func WithClientDisableHTTP2Opt(b bool) ClientCfgOpt {
 		cfg.disableHTTP2 = b
 	}
 }
+
+// WithClientConnChurnOpt forces clients to drop and rebuild connections.
+func WithClientConnChurnOpt(churn *types.ConnChurn) ClientCfgOpt {
+	return func(cfg *clientCfg) {
+		cfg.connChurn = churn
+	}
+}
@@ -4,11 +4,22 @@
 package request
 
 import (
+	"context"
 	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/Azure/kperf/api/types"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/metrics"
+	"k8s.io/kubectl/pkg/scheme"
 )
 
 type transportCacheTracker struct{}
@@ -36,3 +47,128 @@ func TestNewClientShouldNotPanic(t *testing.T) {
 	_, err := NewClients("testdata/dummy_nonexistent_kubeconfig.yaml", 10)
 	assert.NoError(t, err)
 }
+
+func TestNewClientsWithConnChurn(t *testing.T) {
+	clis, err := NewClients("testdata/dummy_nonexistent_kubeconfig.yaml", 2,
+		WithClientConnChurnOpt(&types.ConnChurn{EveryRequests: 2}),
+	)
+	require.NoError(t, err)
+	require.Len(t, clis, 2)
+
+	for _, cli := range clis {
+		_, ok := cli.(Reconnector)
+		assert.True(t, ok)
+	}
+}
+
+func TestChurnClientRebuildsConnections(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	var mu sync.Mutex
+	dials := 0
+	countDials := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return dials
+	}
+
+	dialer := &net.Dialer{}
+	restCfg := &rest.Config{
+		Host:  srv.URL,
+		Proxy: http.ProxyFromEnvironment,
+		Dial: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			mu.Lock()
+			dials++
+			mu.Unlock()
+			return dialer.DialContext(ctx, network, addr)
+		},
+		ContentConfig: rest.ContentConfig{
+			NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		},
+	}
+	cli, err := newChurnClient(restCfg, types.ConnChurn{EveryRequests: 2})
+	require.NoError(t, err)
+
+	get := func() {
+		require.NoError(t, cli.Get().AbsPath("/").Do(context.TODO()).Error())
+	}
+
+	get()
+	get()
+	assert.Equal(t, 1, countDials())
+
+	// The third request reaches EveryRequests and uses new connection.
+	get()
+	assert.Equal(t, 2, countDials())
+
+	cli.Reconnect()
+	get()
+	assert.Equal(t, 3, countDials())
+}
+
+func TestChurnClientClosesOldConnections(t *testing.T) {
+	var mu sync.Mutex
+	openConns := 0
+	releaseCh := make(chan struct{})
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			<-releaseCh
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch state {
+		case http.StateNew:
+			openConns++
+		case http.StateClosed, http.StateHijacked:
+			openConns--
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+
+	countConns := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return openConns
+	}
+
+	restCfg := &rest.Config{
+		Host:  srv.URL,
+		Proxy: http.ProxyFromEnvironment,
+		ContentConfig: rest.ContentConfig{
+			NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		},
+	}
+	cli, err := newChurnClient(restCfg, types.ConnChurn{EveryRequests: 1})
+	require.NoError(t, err)
+
+	limiter := cli.GetRateLimiter()
+
+	// The slow request holds the connection while the client is rebuilt.
+	slowDoneCh := make(chan error, 1)
+	go func() {
+		slowDoneCh <- cli.Get().AbsPath("/slow").Do(context.TODO()).Error()
+	}()
+	require.Eventually(t, func() bool { return countConns() == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, cli.Get().AbsPath("/fast").Do(context.TODO()).Error())
+	}
+	assert.Same(t, limiter, cli.GetRateLimiter())
+
+	// The connections of replaced clients are closed once requests finish,
+	// only the slow request's and current client's connections are left.
+	assert.Eventually(t, func() bool { return countConns() <= 2 }, 5*time.Second, 10*time.Millisecond)
+
+	close(releaseCh)
+	require.NoError(t, <-slowDoneCh)
+	assert.Eventually(t, func() bool { return countConns() <= 1 }, 5*time.Second, 10*time.Millisecond)
+}
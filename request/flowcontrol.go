@@ -0,0 +1,250 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package request
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/Azure/kperf/api/types"
+	"github.com/Azure/kperf/metrics"
+
+	flowcontrolv1 "k8s.io/api/flowcontrol/v1"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/klog/v2"
+	"k8s.io/kubectl/pkg/scheme"
+)
+
+// responseTrackerKey is the context key of responseTracker.
+type responseTrackerKey struct{}
+
+// responseTracker records kube-apiserver's response metadata for one
+// request, including all the retries.
+type responseTracker struct {
+	mu sync.Mutex
+
+	attempts         int
+	flowSchemaUID    string
+	priorityLevelUID string
+}
+
+// withResponseTracker returns a copy of ctx carrying new responseTracker.
+func withResponseTracker(ctx context.Context) (context.Context, *responseTracker) {
+	tracker := &responseTracker{}
+	return context.WithValue(ctx, responseTrackerKey{}, tracker), tracker
+}
+
+// responseTrackerFrom returns responseTracker from context if any.
+func responseTrackerFrom(ctx context.Context) *responseTracker {
+	tracker, _ := ctx.Value(responseTrackerKey{}).(*responseTracker)
+	return tracker
+}
+
+// observe records response's metadata.
+func (t *responseTracker) observe(resp *http.Response) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.attempts++
+	if resp == nil {
+		return
+	}
+
+	// NOTE: kube-apiserver sets these headers after classifying request,
+	// so that rejected requests, like 429, have these headers as well.
+	if uid := resp.Header.Get(flowcontrolv1.ResponseHeaderMatchedFlowSchemaUID); uid != "" {
+		t.flowSchemaUID = uid
+	}
+	if uid := resp.Header.Get(flowcontrolv1.ResponseHeaderMatchedPriorityLevelConfigurationUID); uid != "" {
+		t.priorityLevelUID = uid
+	}
+}
+
+// flowControl returns UIDs of matched PriorityLevelConfiguration and
+// FlowSchema, and the number of retries.
+func (t *responseTracker) flowControl() (priorityLevelUID, flowSchemaUID string, retries int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	retries = t.attempts - 1
+	if retries < 0 {
+		retries = 0
+	}
+	return t.priorityLevelUID, t.flowSchemaUID, retries
+}
+
+// responseTrackerRoundTripper records response's metadata into
+// responseTracker carried by request's context.
+type responseTrackerRoundTripper struct {
+	rt http.RoundTripper
+}
+
+var _ utilnet.RoundTripperWrapper = &responseTrackerRoundTripper{}
+
+// newResponseTrackerRoundTripper wraps http.RoundTripper.
+func newResponseTrackerRoundTripper(rt http.RoundTripper) http.RoundTripper {
+	return &responseTrackerRoundTripper{rt: rt}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (rt *responseTrackerRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := rt.rt.RoundTrip(req)
+	if tracker := responseTrackerFrom(req.Context()); tracker != nil {
+		tracker.observe(resp)
+	}
+	return resp, err
+}
+
+// WrappedRoundTripper implements utilnet.RoundTripperWrapper.
+func (rt *responseTrackerRoundTripper) WrappedRoundTripper() http.RoundTripper {
+	return rt.rt
+}
+
+// flowControlNameCache maps UID to name for PriorityLevelConfigurations and
+// FlowSchemas.
+type flowControlNameCache struct {
+	mu    sync.Mutex
+	names map[string]string
+	// unknown stores the UIDs which can't be resolved after fetching, for
+	// instance, the object has been deleted. They won't trigger fetching
+	// again.
+	unknown map[string]struct{}
+}
+
+func newFlowControlNameCache() *flowControlNameCache {
+	return &flowControlNameCache{
+		names:   map[string]string{},
+		unknown: map[string]struct{}{},
+	}
+}
+
+// lookup returns the function to resolve name by UID. It lists flowcontrol
+// objects from kube-apiserver only if there is new UID. The UID is returned
+// as name if it can't be resolved.
+func (c *flowControlNameCache) lookup(ctx context.Context, cli rest.Interface, uids []string) func(uid string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	needFetch := false
+	for _, uid := range uids {
+		_, known := c.names[uid]
+		_, unknown := c.unknown[uid]
+		if !known && !unknown {
+			needFetch = true
+			break
+		}
+	}
+
+	if needFetch {
+		names, err := fetchFlowControlNames(ctx, cli)
+		if err != nil {
+			klog.V(2).ErrorS(err, "failed to resolve flowcontrol names, use UIDs instead")
+		} else {
+			for uid, name := range names {
+				c.names[uid] = name
+			}
+			for _, uid := range uids {
+				if _, ok := c.names[uid]; !ok {
+					c.unknown[uid] = struct{}{}
+				}
+			}
+		}
+	}
+
+	names := make(map[string]string, len(uids))
+	for _, uid := range uids {
+		if name, ok := c.names[uid]; ok {
+			names[uid] = name
+		}
+	}
+	return func(uid string) string {
+		if name, ok := names[uid]; ok {
+			return name
+		}
+		return uid
+	}
+}
+
+// NewFlowControlClient returns the client to resolve flowcontrol objects'
+// names after benchmark. It isn't limited by QPS so that it doesn't compete
+// with benchmark's clients.
+func NewFlowControlClient(kubeCfgPath string, userAgent string) (rest.Interface, error) {
+	restCfg, err := clientcmd.BuildConfigFromFlags("", kubeCfgPath)
+	if err != nil {
+		return nil, err
+	}
+	restCfg.NegotiatedSerializer = scheme.Codecs.WithoutConversion()
+	restCfg.RateLimiter = flowcontrol.NewFakeAlwaysRateLimiter()
+	if userAgent != "" {
+		restCfg.UserAgent = userAgent
+	}
+	return rest.UnversionedRESTClientFor(restCfg)
+}
+
+// fetchFlowControlNames returns the map from UID to name for all the
+// PriorityLevelConfigurations and FlowSchemas.
+func fetchFlowControlNames(ctx context.Context, cli rest.Interface) (map[string]string, error) {
+	res := map[string]string{}
+
+	plcs := &flowcontrolv1.PriorityLevelConfigurationList{}
+	err := cli.Get().
+		AbsPath("apis", flowcontrolv1.GroupName, "v1", "prioritylevelconfigurations").
+		Do(ctx).
+		Into(plcs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list prioritylevelconfigurations: %w", err)
+	}
+	for _, plc := range plcs.Items {
+		res[string(plc.UID)] = plc.Name
+	}
+
+	fss := &flowcontrolv1.FlowSchemaList{}
+	err = cli.Get().
+		AbsPath("apis", flowcontrolv1.GroupName, "v1", "flowschemas").
+		Do(ctx).
+		Into(fss)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list flowschemas: %w", err)
+	}
+	for _, fs := range fss.Items {
+		res[string(fs.UID)] = fs.Name
+	}
+	return res, nil
+}
+
+// resolveFlowControlNames replaces UIDs with names in PriorityLevelStats. It
+// keeps UIDs if cli is nil or it fails to fetch names from kube-apiserver.
+func resolveFlowControlNames(ctx context.Context, cache *flowControlNameCache, cli rest.Interface, stats map[string]*types.PriorityLevelStats) map[string]*types.PriorityLevelStats {
+	if len(stats) == 0 || cli == nil {
+		return stats
+	}
+
+	uids := make([]string, 0, len(stats))
+	for uid, s := range stats {
+		uids = append(uids, uid)
+		for fsUID := range s.FlowSchemas {
+			uids = append(uids, fsUID)
+		}
+	}
+	nameOf := cache.lookup(ctx, cli, uids)
+
+	res := make(map[string]*types.PriorityLevelStats, len(stats))
+	for uid, s := range stats {
+		flowSchemas := make(map[string]int, len(s.FlowSchemas))
+		for fsUID, n := range s.FlowSchemas {
+			flowSchemas[nameOf(fsUID)] += n
+		}
+		s.FlowSchemas = flowSchemas
+
+		// NOTE: Different UIDs could share the same name if the object
+		// has been re-created during benchmark.
+		metrics.MergePriorityLevelStats(res, map[string]*types.PriorityLevelStats{nameOf(uid): s})
+	}
+	return res
+}
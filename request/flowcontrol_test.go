@@ -0,0 +1,93 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package request
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	flowcontrolv1 "k8s.io/api/flowcontrol/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/kubectl/pkg/scheme"
+)
+
+func TestResponseTrackerRoundTripper(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set(flowcontrolv1.ResponseHeaderMatchedFlowSchemaUID, "fs-uid")
+		w.Header().Set(flowcontrolv1.ResponseHeaderMatchedPriorityLevelConfigurationUID, "pl-uid")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	cli := &http.Client{Transport: newResponseTrackerRoundTripper(http.DefaultTransport)}
+
+	ctx, tracker := withResponseTracker(context.Background())
+	for i := 0; i < 3; i++ {
+		req, err := http.NewRequestWithContext(ctx, "GET", srv.URL, nil)
+		require.NoError(t, err)
+
+		resp, err := cli.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	priorityLevel, flowSchema, retries := tracker.flowControl()
+	assert.Equal(t, "pl-uid", priorityLevel)
+	assert.Equal(t, "fs-uid", flowSchema)
+	assert.Equal(t, 2, retries)
+}
+
+func TestFlowControlNameCache(t *testing.T) {
+	var lists int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&lists, 1)
+
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/prioritylevelconfigurations"):
+			_, _ = w.Write([]byte(`{"items":[{"metadata":{"name":"workload-low","uid":"pl-uid"}}]}`))
+		case strings.HasSuffix(r.URL.Path, "/flowschemas"):
+			_, _ = w.Write([]byte(`{"items":[{"metadata":{"name":"service-accounts","uid":"fs-uid"}}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	cli, err := rest.UnversionedRESTClientFor(&rest.Config{
+		Host:  srv.URL,
+		Proxy: http.ProxyFromEnvironment,
+		ContentConfig: rest.ContentConfig{
+			NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		},
+	})
+	require.NoError(t, err)
+
+	cache := newFlowControlNameCache()
+	ctx := context.Background()
+
+	nameOf := cache.lookup(ctx, cli, []string{"pl-uid", "fs-uid"})
+	assert.Equal(t, "workload-low", nameOf("pl-uid"))
+	assert.Equal(t, "service-accounts", nameOf("fs-uid"))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&lists))
+
+	// Known UIDs don't list again.
+	nameOf = cache.lookup(ctx, cli, []string{"pl-uid"})
+	assert.Equal(t, "workload-low", nameOf("pl-uid"))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&lists))
+
+	// Unknown UID lists once and falls back to UID.
+	nameOf = cache.lookup(ctx, cli, []string{"deleted-uid"})
+	assert.Equal(t, "deleted-uid", nameOf("deleted-uid"))
+	assert.Equal(t, int32(4), atomic.LoadInt32(&lists))
+
+	_ = cache.lookup(ctx, cli, []string{"deleted-uid", "pl-uid"})
+	assert.Equal(t, int32(4), atomic.LoadInt32(&lists))
+}
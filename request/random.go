@@ -59,6 +59,10 @@ func NewWeightedRandomRequests(spec *types.LoadProfileSpec) (*WeightedRandomRequ
 		default:
 			return nil, fmt.Errorf("not implement for PUT yet")
 		}
+
+		if r.Tag != "" {
+			builder = &taggedRequestBuilder{RESTRequestBuilder: builder, tag: r.Tag}
+		}
 		reqBuilders = append(reqBuilders, builder)
 	}
 
@@ -132,6 +136,33 @@ type RESTRequestBuilder interface {
 	Build(cli rest.Interface) Requester
 }
 
+// taggedRequestBuilder builds request with tag.
+type taggedRequestBuilder struct {
+	RESTRequestBuilder
+	tag string
+}
+
+// Build implements RequestBuilder.Build.
+func (b *taggedRequestBuilder) Build(cli rest.Interface) Requester {
+	return &taggedRequester{
+		Requester: b.RESTRequestBuilder.Build(cli),
+		tag:       b.tag,
+	}
+}
+
+// scopeOf returns request's scope, which is aligned with kube-apiserver's
+// apiserver_request_total metric.
+func scopeOf(namespace, name string) string {
+	switch {
+	case name != "":
+		return "resource"
+	case namespace != "":
+		return "namespace"
+	default:
+		return "cluster"
+	}
+}
+
 type requestGetBuilder struct {
 	version         schema.GroupVersion
 	resource        string
@@ -168,7 +199,9 @@ func (b *requestGetBuilder) Build(cli rest.Interface) Requester {
 
 	return &DiscardRequester{
 		BaseRequester: BaseRequester{
-			method: "GET",
+			method:   "GET",
+			resource: schema.GroupResource{Group: b.version.Group, Resource: b.resource},
+			scope:    scopeOf(b.namespace, b.name),
 			req: cli.Get().AbsPath(comps...).
 				SpecificallyVersionedParams(
 					&metav1.GetOptions{ResourceVersion: b.resourceVersion},
@@ -222,7 +255,9 @@ func (b *requestListBuilder) Build(cli rest.Interface) Requester {
 
 	return &DiscardRequester{
 		BaseRequester: BaseRequester{
-			method: "LIST",
+			method:   "LIST",
+			resource: schema.GroupResource{Group: b.version.Group, Resource: b.resource},
+			scope:    scopeOf(b.namespace, ""),
 			req: cli.Get().AbsPath(comps...).
 				SpecificallyVersionedParams(
 					&metav1.ListOptions{
@@ -277,7 +312,9 @@ func (b *requestWatchListBuilder) Build(cli rest.Interface) Requester {
 
 	return &WatchListRequester{
 		BaseRequester: BaseRequester{
-			method: "WATCHLIST",
+			method:   "WATCHLIST",
+			resource: schema.GroupResource{Group: b.version.Group, Resource: b.resource},
+			scope:    scopeOf(b.namespace, ""),
 			req: cli.Get().AbsPath(comps...).
 				SpecificallyVersionedParams(
 					&metav1.ListOptions{
@@ -333,7 +370,9 @@ func (b *requestGetPodLogBuilder) Build(cli rest.Interface) Requester {
 
 	return &DiscardRequester{
 		BaseRequester: BaseRequester{
-			method: "POD_LOG",
+			method:   "POD_LOG",
+			resource: schema.GroupResource{Resource: "pods/log"},
+			scope:    scopeOf(b.namespace, b.name),
 			req: cli.Get().AbsPath(comps...).
 				SpecificallyVersionedParams(
 					&corev1.PodLogOptions{
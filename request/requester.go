@@ -9,6 +9,8 @@ import (
 	"time"
 	_ "unsafe" // unsafe to use internal function from client-go
 
+	"github.com/Azure/kperf/metrics"
+
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/rest"
@@ -18,20 +20,34 @@ import (
 
 type Requester interface {
 	Method() string
+	Info() metrics.RequestInfo
 	URL() *url.URL
 	Timeout(time.Duration)
 	Do(context.Context) (bytes int64, err error)
 }
 
 type BaseRequester struct {
-	method string
-	req    *rest.Request
+	method   string
+	resource schema.GroupResource
+	// scope is cluster, namespace or resource.
+	scope string
+	req   *rest.Request
 }
 
 func (reqr *BaseRequester) Method() string {
 	return reqr.method
 }
 
+func (reqr *BaseRequester) Info() metrics.RequestInfo {
+	return metrics.RequestInfo{
+		URL:      reqr.req.URL().String(),
+		Verb:     reqr.method,
+		Group:    reqr.resource.Group,
+		Resource: reqr.resource.Resource,
+		Scope:    reqr.scope,
+	}
+}
+
 func (reqr *BaseRequester) URL() *url.URL {
 	return reqr.req.URL()
 }
@@ -80,6 +96,18 @@ func (reqr *WatchListRequester) Do(ctx context.Context) (zero int64, _ error) {
 	return zero, fmt.Errorf("don't receive bookmark")
 }
 
+// taggedRequester adds tag into request's information.
+type taggedRequester struct {
+	Requester
+	tag string
+}
+
+func (reqr *taggedRequester) Info() metrics.RequestInfo {
+	info := reqr.Requester.Info()
+	info.Tag = reqr.tag
+	return info
+}
+
 //go:linkname handleAnyWatch k8s.io/client-go/tools/cache.handleAnyWatch
 func handleAnyWatch(start time.Time,
 	w watch.Interface,
@@ -6,8 +6,11 @@ package request
 import (
 	"context"
 	"errors"
+	"fmt"
 	"math"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Azure/kperf/api/types"
@@ -21,6 +24,17 @@ import (
 
 const defaultTimeout = 60 * time.Second
 
+// selfMonitorInterval is the interval to sample runner's own resource usage.
+const selfMonitorInterval = time.Second
+
+// drainTimeout is the time to wait for in-flight requests after benchmark is
+// interrupted. The requests still running after that will be cancelled.
+const drainTimeout = 10 * time.Second
+
+// flowControlResolveTimeout is the time to resolve flowcontrol objects' names
+// after benchmark. The UIDs are reported if it times out.
+const flowControlResolveTimeout = 10 * time.Second
+
 // Result contains responseStats vlaues from Gather() and adds Duration and Total values separately
 type Result struct {
 	types.ResponseStats
@@ -28,10 +42,24 @@ type Result struct {
 	Duration time.Duration
 	// Total means the total number of requests.
 	Total int
+	// SelfStats is runner's own resource usage during benchmark.
+	SelfStats *types.RunnerSelfStats
+	// Warnings shows the issues which might make result unreliable.
+	Warnings []string
+	// Interrupted means benchmark was cancelled before all the requests
+	// were sent. Total is the number of finished requests in that case.
+	Interrupted bool
 }
 
 // Schedule files requests to apiserver based on LoadProfileSpec.
-func Schedule(ctx context.Context, spec *types.LoadProfileSpec, restCli []rest.Interface) (*Result, error) {
+//
+// If ctx is cancelled, Schedule stops sending new requests, waits for
+// in-flight requests up to drainTimeout and returns what has been gathered
+// so far.
+//
+// The flowControlCli resolves UIDs of matched PriorityLevelConfigurations and
+// FlowSchemas into names after benchmark. The UIDs are reported if it's nil.
+func Schedule(ctx context.Context, spec *types.LoadProfileSpec, restCli []rest.Interface, flowControlCli rest.Interface, metricOpts ...metrics.ResponseMetricOpt) (*Result, error) {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
@@ -54,7 +82,27 @@ func Schedule(ctx context.Context, spec *types.LoadProfileSpec, restCli []rest.I
 	reqBuilderCh := rndReqs.Chan()
 	var wg sync.WaitGroup
 
-	respMetric := metrics.NewResponseMetric()
+	monitor := metrics.NewSelfMonitor(selfMonitorInterval)
+	monitor.Start()
+
+	// dispatched is the number of dispatched requests, used to calculate
+	// intended dispatch time based on rate.
+	dispatched := int64(0)
+	dispatchStart := time.Now()
+
+	// finished is the number of requests which have got response or error.
+	finished := int64(0)
+
+	// drainCtx is the parent of all the requests so that in-flight requests
+	// can be cancelled if they can't finish in drainTimeout.
+	drainCtx, drainCancel := context.WithCancel(context.Background())
+	defer drainCancel()
+
+	respMetric := metrics.NewResponseMetric(
+		append([]metrics.ResponseMetricOpt{
+			metrics.WithTimeSeriesIntervalOpt(spec.TimeSeriesInterval),
+		}, metricOpts...)...,
+	)
 	for i := 0; i < clients; i++ {
 		// reuse connection if clients > conns
 		cli := restCli[i%len(restCli)]
@@ -64,21 +112,35 @@ func Schedule(ctx context.Context, spec *types.LoadProfileSpec, restCli []rest.I
 
 			for builder := range reqBuilderCh {
 				req := builder.Build(cli)
+				info := req.Info()
 
+				waitStart := time.Now()
 				if err := limiter.Wait(ctx); err != nil {
 					klog.V(5).Infof("Rate limiter wait failed: %v", err)
 					cancel()
 					return
 				}
 
-				klog.V(5).Infof("Request URL: %s", req.URL())
+				if spec.Rate > 0 {
+					seq := atomic.AddInt64(&dispatched, 1) - 1
+					intended := dispatchStart.Add(time.Duration(float64(seq) / spec.Rate * float64(time.Second)))
+					monitor.ObserveLimiterLag(math.Max(0, time.Since(intended).Seconds()))
+				}
+
+				klog.V(5).Infof("Request URL: %s", info.URL)
 
 				req.Timeout(defaultTimeout)
 				func() {
+					defer atomic.AddInt64(&finished, 1)
+
 					start := time.Now()
+					respMetric.ObserveDispatch(info, start.Sub(waitStart).Seconds())
+
+					reqCtx, tracker := withResponseTracker(drainCtx)
+					reqCtx, span := startRequestSpan(reqCtx, info)
 
 					var bytes int64
-					bytes, err := req.Do(context.Background())
+					bytes, err := req.Do(reqCtx)
 					// Based on HTTP2 Spec Section 8.1 [1],
 					//
 					// A server can send a complete response prior to the client
@@ -103,12 +165,18 @@ func Schedule(ctx context.Context, spec *types.LoadProfileSpec, restCli []rest.I
 					latency := end.Sub(start).Seconds()
 
 					respMetric.ObserveReceivedBytes(bytes)
+
+					priorityLevel, flowSchema, retries := tracker.flowControl()
+					respMetric.ObserveFlowControl(priorityLevel, flowSchema, latency, retries, err)
+					endRequestSpan(span, bytes, retries, err)
+
 					if err != nil {
-						respMetric.ObserveFailure(req.URL().String(), end, latency, err)
+						info.PriorityLevel = priorityLevel
+						respMetric.ObserveFailure(info, end, latency, err)
 						klog.V(5).Infof("Request stream failed: %v", err)
 						return
 					}
-					respMetric.ObserveLatency(req.URL().String(), latency)
+					respMetric.ObserveLatency(info, latency)
 				}()
 			}
 		}(cli)
@@ -125,19 +193,141 @@ func Schedule(ctx context.Context, spec *types.LoadProfileSpec, restCli []rest.I
 
 	start := time.Now()
 
+	if churn := spec.ConnChurn; churn != nil && churn.StormAfter > 0 {
+		go triggerReconnectStorm(ctx, churn.StormAfter, restCli)
+	}
+
 	rndReqs.Run(ctx, spec.Total)
 	rndReqs.Stop()
-	wg.Wait()
+
+	interrupted := waitForInFlightRequests(ctx, &wg, drainTimeout, drainCancel)
 
 	totalDuration := time.Since(start)
 	responseStats := respMetric.Gather()
+	selfStats := monitor.Stop()
+
+	if len(responseStats.PriorityLevels) > 0 {
+		resolveCtx, resolveCancel := context.WithTimeout(context.Background(), flowControlResolveTimeout)
+		responseStats.PriorityLevels = resolveFlowControlNames(resolveCtx, newFlowControlNameCache(), flowControlCli, responseStats.PriorityLevels)
+		resolveCancel()
+	}
+	total := spec.Total
+	if interrupted {
+		total = int(atomic.LoadInt64(&finished))
+		klog.Warningf("Benchmark was interrupted after %v, %d of %d requests finished", totalDuration, total, spec.Total)
+	}
+
+	warnings := clientBottleneckWarnings(spec.Rate, total, totalDuration, selfStats)
+	for _, w := range warnings {
+		klog.Warning(w)
+	}
+
 	return &Result{
 		ResponseStats: responseStats,
 		Duration:      totalDuration,
-		Total:         spec.Total,
+		Total:         total,
+		SelfStats:     selfStats,
+		Warnings:      warnings,
+		Interrupted:   interrupted,
 	}, nil
 }
 
+// waitForInFlightRequests waits for all the clients to exit. If ctx is
+// cancelled, it waits at most timeout and then calls cancelRequests to abort
+// the requests still running. It returns true if ctx has been cancelled.
+func waitForInFlightRequests(ctx context.Context, wg *sync.WaitGroup, timeout time.Duration, cancelRequests context.CancelFunc) bool {
+	doneCh := make(chan struct{})
+	go func() {
+		defer close(doneCh)
+		wg.Wait()
+	}()
+
+	select {
+	case <-doneCh:
+		return ctx.Err() != nil
+	case <-ctx.Done():
+	}
+
+	klog.V(2).InfoS("Draining in-flight requests", "timeout", timeout)
+	select {
+	case <-doneCh:
+	case <-time.After(timeout):
+		klog.V(2).Infof("Cancelling in-flight requests after %v", timeout)
+		cancelRequests()
+		<-doneCh
+	}
+	return true
+}
+
+const (
+	// minAchievedRateRatio is the ratio of achieved rate to expected rate,
+	// below which the runner checks whether it's the bottleneck.
+	minAchievedRateRatio = 0.9
+	// maxCPUUsageRatio is the ratio of CPU usage to available cores, above
+	// which the runner is considered CPU-bound.
+	maxCPUUsageRatio = 0.9
+	// maxThrottledRatio is the ratio of throttled time to benchmark time,
+	// above which the runner is considered CPU-throttled.
+	maxThrottledRatio = 0.1
+	// maxGCPauseRatio is the ratio of GC pause time to benchmark time,
+	// above which the runner is considered GC-bound.
+	maxGCPauseRatio = 0.05
+	// maxLimiterLag is p99 delay of dispatch, above which the runner doesn't
+	// have enough clients to sustain the rate.
+	maxLimiterLag = time.Second
+)
+
+// clientBottleneckWarnings returns warnings if achieved rate is lower than
+// expected because of runner, instead of apiserver.
+func clientBottleneckWarnings(expected float64, total int, duration time.Duration, stats *types.RunnerSelfStats) []string {
+	if expected <= 0 || duration <= 0 || stats == nil {
+		return nil
+	}
+
+	achieved := float64(total) / duration.Seconds()
+	if achieved >= expected*minAchievedRateRatio {
+		return nil
+	}
+
+	reasons := []string{}
+	if stats.ThrottledSeconds > duration.Seconds()*maxThrottledRatio {
+		reasons = append(reasons, fmt.Sprintf("runner was CPU-throttled for %.2fs", stats.ThrottledSeconds))
+	}
+	if stats.AvailableCPUCores > 0 && stats.AvgCPUCores >= stats.AvailableCPUCores*maxCPUUsageRatio {
+		reasons = append(reasons, fmt.Sprintf("runner used %.2f of %.2f CPU cores", stats.AvgCPUCores, stats.AvailableCPUCores))
+	}
+	if stats.GCPauseSeconds > duration.Seconds()*maxGCPauseRatio {
+		reasons = append(reasons, fmt.Sprintf("GC paused runner for %.2fs", stats.GCPauseSeconds))
+	}
+	if lag, ok := metrics.PercentileValue(stats.PercentileLimiterLags, 0.99); ok && lag > maxLimiterLag.Seconds() {
+		reasons = append(reasons, fmt.Sprintf("p99 dispatch lag is %.2fs, all clients might be busy", lag))
+	}
+	if len(reasons) == 0 {
+		return nil
+	}
+	return []string{
+		fmt.Sprintf("achieved rate %.2f is lower than expected rate %.2f because of runner: %s",
+			achieved, expected, strings.Join(reasons, "; ")),
+	}
+}
+
+// triggerReconnectStorm forces all the clients to reconnect at once after
+// the given duration.
+func triggerReconnectStorm(ctx context.Context, after time.Duration, restCli []rest.Interface) {
+	select {
+	case <-ctx.Done():
+		return
+	case <-time.After(after):
+	}
+
+	klog.V(2).InfoS("Triggering reconnect storm", "connections", len(restCli))
+	for _, cli := range restCli {
+		if r, ok := cli.(Reconnector); ok {
+			r.Reconnect()
+		}
+	}
+}
+
 // isHTTP2StreamNoError returns true if it's NO_ERROR.
 func isHTTP2StreamNoError(err error) bool {
 	if err == nil {
@@ -0,0 +1,73 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package request
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/kperf/api/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientBottleneckWarnings(t *testing.T) {
+	idle := &types.RunnerSelfStats{
+		AvgCPUCores:       0.1,
+		AvailableCPUCores: 2,
+	}
+
+	// achieved rate is close to expected one
+	assert.Nil(t, clientBottleneckWarnings(100, 1000, 10*time.Second, idle))
+
+	// apiserver is the bottleneck
+	assert.Nil(t, clientBottleneckWarnings(100, 500, 10*time.Second, idle))
+
+	// rate is unlimited
+	assert.Nil(t, clientBottleneckWarnings(0, 500, 10*time.Second, idle))
+
+	busy := &types.RunnerSelfStats{
+		AvgCPUCores:           1.9,
+		AvailableCPUCores:     2,
+		ThrottledSeconds:      3,
+		PercentileLimiterLags: [][2]float64{{0.99, 2}},
+	}
+	warnings := clientBottleneckWarnings(100, 500, 10*time.Second, busy)
+	require.Len(t, warnings, 1)
+	assert.Contains(t, warnings[0], "CPU-throttled")
+	assert.Contains(t, warnings[0], "1.90 of 2.00 CPU cores")
+	assert.Contains(t, warnings[0], "dispatch lag")
+}
+
+func TestWaitForInFlightRequests(t *testing.T) {
+	// all the requests finished without interruption
+	var wg sync.WaitGroup
+	assert.False(t, waitForInFlightRequests(context.Background(), &wg, time.Second, func() {}))
+
+	// in-flight request finishes in time after interruption
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		time.Sleep(10 * time.Millisecond)
+	}()
+	assert.True(t, waitForInFlightRequests(ctx, &wg, time.Minute, func() {}))
+
+	// in-flight request is cancelled after timeout
+	reqCtx, reqCancel := context.WithCancel(context.Background())
+	defer reqCancel()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		<-reqCtx.Done()
+	}()
+	assert.True(t, waitForInFlightRequests(ctx, &wg, 10*time.Millisecond, reqCancel))
+	assert.Error(t, reqCtx.Err())
+}
@@ -0,0 +1,271 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package request
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/kperf/api/types"
+	"github.com/Azure/kperf/metrics"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
+)
+
+// SearchMode is the strategy to find the maximum sustainable rate.
+type SearchMode string
+
+const (
+	// SearchModeRamp increases rate by fixed step until one step fails.
+	SearchModeRamp SearchMode = "ramp"
+	// SearchModeBinary uses binary search between minimum and maximum rate.
+	SearchModeBinary SearchMode = "binary"
+)
+
+// SearchConfig defines how to search the maximum sustainable rate.
+type SearchConfig struct {
+	// Mode is the search strategy.
+	Mode SearchMode
+	// MinRate is the lower bound of rate.
+	MinRate float64
+	// MaxRate is the upper bound of rate.
+	MaxRate float64
+	// Step is the rate increment for ramp mode.
+	Step float64
+	// Precision stops binary search when the range is narrower than it.
+	Precision float64
+	// StepDuration is the expected duration of each step. The number of
+	// requests in each step is rate * StepDuration.
+	StepDuration time.Duration
+	// Cooldown is the pause between two steps so that kube-apiserver can
+	// recover from previous step.
+	Cooldown time.Duration
+	// P99Threshold is the maximum p99 latency (zero is no limit).
+	P99Threshold time.Duration
+	// ErrorRatioThreshold is the maximum ratio of failed requests.
+	ErrorRatioThreshold float64
+	// MinAchievedRatio is the minimum ratio of achieved rate to target
+	// rate. The step fails if client can't send requests fast enough.
+	MinAchievedRatio float64
+}
+
+// Validate verifies fields of SearchConfig.
+func (cfg SearchConfig) Validate() error {
+	switch cfg.Mode {
+	case SearchModeRamp:
+		if cfg.Step <= 0 {
+			return fmt.Errorf("step requires > 0: %v", cfg.Step)
+		}
+	case SearchModeBinary:
+		if cfg.Precision <= 0 {
+			return fmt.Errorf("precision requires > 0: %v", cfg.Precision)
+		}
+	default:
+		return fmt.Errorf("unsupported search mode %s", cfg.Mode)
+	}
+
+	if cfg.MinRate <= 0 {
+		return fmt.Errorf("min rate requires > 0: %v", cfg.MinRate)
+	}
+
+	if cfg.MaxRate < cfg.MinRate {
+		return fmt.Errorf("max rate (%v) requires >= min rate (%v)", cfg.MaxRate, cfg.MinRate)
+	}
+
+	if cfg.StepDuration <= 0 {
+		return fmt.Errorf("step duration requires > 0: %v", cfg.StepDuration)
+	}
+
+	if cfg.ErrorRatioThreshold < 0 || cfg.ErrorRatioThreshold > 1 {
+		return fmt.Errorf("error ratio threshold requires [0, 1]: %v", cfg.ErrorRatioThreshold)
+	}
+
+	if cfg.MinAchievedRatio < 0 || cfg.MinAchievedRatio > 1 {
+		return fmt.Errorf("min achieved ratio requires [0, 1]: %v", cfg.MinAchievedRatio)
+	}
+	return nil
+}
+
+// Search runs load profile at different rates and returns the highest rate
+// which passes all the thresholds.
+func Search(ctx context.Context, spec *types.LoadProfileSpec, restCli []rest.Interface, flowControlCli rest.Interface, cfg SearchConfig, metricOpts ...metrics.ResponseMetricOpt) (*types.RunnerSearchReport, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid search config: %w", err)
+	}
+
+	first := true
+	probe := func(rate float64) (*types.RunnerSearchStep, error) {
+		if !first && cfg.Cooldown > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(cfg.Cooldown):
+			}
+		}
+		first = false
+
+		stepSpec := *spec
+		stepSpec.Rate = rate
+		stepSpec.Total = int(rate * cfg.StepDuration.Seconds())
+		if stepSpec.Total < 1 {
+			stepSpec.Total = 1
+		}
+
+		klog.V(2).InfoS("Searching", "rate", rate, "total", stepSpec.Total)
+
+		res, err := Schedule(ctx, &stepSpec, restCli, flowControlCli, metricOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to run at rate %v: %w", rate, err)
+		}
+		// NOTE: The interrupted step doesn't tell whether rate passes.
+		if res.Interrupted {
+			return nil, ctx.Err()
+		}
+
+		step := evaluateSearchStep(cfg, rate, res)
+		klog.V(2).InfoS("Searched", "rate", rate, "passed", step.Passed, "reason", step.Reason)
+		return step, nil
+	}
+	return searchMaxRate(cfg, probe)
+}
+
+// searchMaxRate finds the highest passing rate by probe.
+func searchMaxRate(cfg SearchConfig, probe func(rate float64) (*types.RunnerSearchStep, error)) (*types.RunnerSearchReport, error) {
+	report := &types.RunnerSearchReport{
+		Mode: string(cfg.Mode),
+	}
+
+	run := func(rate float64) (bool, error) {
+		step, err := probe(rate)
+		if err != nil {
+			return false, err
+		}
+		report.Steps = append(report.Steps, *step)
+		return step.Passed, nil
+	}
+
+	if err := searchByMode(cfg, report, run); err != nil {
+		if !errors.Is(err, context.Canceled) {
+			return nil, err
+		}
+		// NOTE: Keep the steps finished before interruption.
+		report.Interrupted = true
+	}
+
+	sort.SliceStable(report.Steps, func(i, j int) bool {
+		return report.Steps[i].Rate < report.Steps[j].Rate
+	})
+	return report, nil
+}
+
+// searchByMode runs rates by search mode and updates report's MaxRate.
+func searchByMode(cfg SearchConfig, report *types.RunnerSearchReport, run func(rate float64) (bool, error)) error {
+	switch cfg.Mode {
+	case SearchModeRamp:
+		// NOTE: Use step index instead of accumulating float so that
+		// MaxRate isn't skipped because of rounding error.
+		steps := int(math.Floor((cfg.MaxRate-cfg.MinRate)/cfg.Step + 1e-9))
+		for i := 0; i <= steps; i++ {
+			rate := math.Min(cfg.MinRate+float64(i)*cfg.Step, cfg.MaxRate)
+
+			passed, err := run(rate)
+			if err != nil {
+				return err
+			}
+			if !passed {
+				break
+			}
+			report.MaxRate = rate
+		}
+	case SearchModeBinary:
+		lo, hi := cfg.MinRate, cfg.MaxRate
+
+		passed, err := run(lo)
+		if err != nil {
+			return err
+		}
+		if !passed {
+			break
+		}
+		report.MaxRate = lo
+
+		if hi == lo {
+			break
+		}
+
+		passed, err = run(hi)
+		if err != nil {
+			return err
+		}
+		if passed {
+			report.MaxRate = hi
+			break
+		}
+
+		for hi-lo > cfg.Precision {
+			mid := (lo + hi) / 2
+
+			passed, err = run(mid)
+			if err != nil {
+				return err
+			}
+			if passed {
+				lo = mid
+				report.MaxRate = mid
+			} else {
+				hi = mid
+			}
+		}
+	}
+	return nil
+}
+
+// evaluateSearchStep checks step's result against thresholds.
+func evaluateSearchStep(cfg SearchConfig, rate float64, res *Result) *types.RunnerSearchStep {
+	histograms := make([]*types.LatencyHistogram, 0, len(res.StatsByResource))
+	for _, s := range res.StatsByResource {
+		histograms = append(histograms, s.Histogram)
+	}
+	latencies := metrics.MergeHistograms(histograms...)
+
+	failed := 0
+	for _, n := range res.ErrorStats {
+		failed += int(n)
+	}
+	total := int(latencies.Count()) + failed
+
+	step := &types.RunnerSearchStep{
+		Rate:                rate,
+		Total:               total,
+		Duration:            res.Duration.String(),
+		PercentileLatencies: latencies.PercentileLatencies(),
+	}
+	if total > 0 {
+		step.ErrorRatio = float64(failed) / float64(total)
+	}
+	if secs := res.Duration.Seconds(); secs > 0 {
+		step.AchievedRate = float64(total) / secs
+	}
+
+	reasons := []string{}
+	if p99, _ := metrics.PercentileValue(step.PercentileLatencies, 0.99); cfg.P99Threshold > 0 && p99 > cfg.P99Threshold.Seconds() {
+		reasons = append(reasons, fmt.Sprintf("p99 latency %.3fs > %v", p99, cfg.P99Threshold))
+	}
+	if step.ErrorRatio > cfg.ErrorRatioThreshold {
+		reasons = append(reasons, fmt.Sprintf("error ratio %.4f > %v", step.ErrorRatio, cfg.ErrorRatioThreshold))
+	}
+	if step.AchievedRate < rate*cfg.MinAchievedRatio {
+		reasons = append(reasons, fmt.Sprintf("achieved rate %.2f < %.2f", step.AchievedRate, rate*cfg.MinAchievedRatio))
+	}
+
+	step.Passed = len(reasons) == 0
+	step.Reason = strings.Join(reasons, "; ")
+	return step
+}
@@ -0,0 +1,130 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package request
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Azure/kperf/api/types"
+	"github.com/Azure/kperf/metrics"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchMaxRate(t *testing.T) {
+	probe := func(rates *[]float64) func(float64) (*types.RunnerSearchStep, error) {
+		return func(rate float64) (*types.RunnerSearchStep, error) {
+			*rates = append(*rates, rate)
+			return &types.RunnerSearchStep{Rate: rate, Passed: rate <= 73}, nil
+		}
+	}
+
+	for _, tc := range []struct {
+		name          string
+		cfg           SearchConfig
+		expectedMax   float64
+		expectedRates []float64
+	}{
+		{
+			name:          "ramp",
+			cfg:           SearchConfig{Mode: SearchModeRamp, MinRate: 10, MaxRate: 100, Step: 20},
+			expectedMax:   70,
+			expectedRates: []float64{10, 30, 50, 70, 90},
+		},
+		{
+			name:          "ramp all passed",
+			cfg:           SearchConfig{Mode: SearchModeRamp, MinRate: 10, MaxRate: 50, Step: 20},
+			expectedMax:   50,
+			expectedRates: []float64{10, 30, 50},
+		},
+		{
+			name:          "ramp with fractional step",
+			cfg:           SearchConfig{Mode: SearchModeRamp, MinRate: 0.1, MaxRate: 0.3, Step: 0.1},
+			expectedMax:   0.3,
+			expectedRates: []float64{0.1, 0.2, 0.3},
+		},
+		{
+			name:          "binary",
+			cfg:           SearchConfig{Mode: SearchModeBinary, MinRate: 10, MaxRate: 90, Precision: 5},
+			expectedMax:   70,
+			expectedRates: []float64{10, 90, 50, 70, 80, 75},
+		},
+		{
+			name:          "binary none passed",
+			cfg:           SearchConfig{Mode: SearchModeBinary, MinRate: 80, MaxRate: 90, Precision: 5},
+			expectedMax:   0,
+			expectedRates: []float64{80},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rates := []float64{}
+			report, err := searchMaxRate(tc.cfg, probe(&rates))
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expectedMax, report.MaxRate)
+			assert.Equal(t, tc.expectedRates, rates)
+			assert.Len(t, report.Steps, len(tc.expectedRates))
+			for i := 1; i < len(report.Steps); i++ {
+				assert.LessOrEqual(t, report.Steps[i-1].Rate, report.Steps[i].Rate)
+			}
+		})
+	}
+}
+
+func TestSearchMaxRateInterrupted(t *testing.T) {
+	probe := func(rate float64) (*types.RunnerSearchStep, error) {
+		if rate > 30 {
+			return nil, context.Canceled
+		}
+		return &types.RunnerSearchStep{Rate: rate, Passed: true}, nil
+	}
+
+	report, err := searchMaxRate(SearchConfig{Mode: SearchModeRamp, MinRate: 10, MaxRate: 100, Step: 10}, probe)
+	require.NoError(t, err)
+	assert.True(t, report.Interrupted)
+	assert.Equal(t, float64(30), report.MaxRate)
+	assert.Len(t, report.Steps, 3)
+
+	_, err = searchMaxRate(SearchConfig{Mode: SearchModeRamp, MinRate: 10, MaxRate: 100, Step: 10},
+		func(float64) (*types.RunnerSearchStep, error) { return nil, fmt.Errorf("boom") })
+	assert.Error(t, err)
+}
+
+func TestEvaluateSearchStep(t *testing.T) {
+	cfg := SearchConfig{
+		P99Threshold:        time.Second,
+		ErrorRatioThreshold: 0.1,
+		MinAchievedRatio:    0.9,
+	}
+
+	h := metrics.NewHistogram()
+	for _, l := range []float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9} {
+		h.Observe(l)
+	}
+
+	res := &Result{
+		ResponseStats: types.ResponseStats{
+			StatsByResource: map[string]*types.RequestStats{
+				"LIST pods cluster": {Histogram: h.Snapshot()},
+			},
+			ErrorStats: map[string]int32{"unknown/oops": 1},
+		},
+		Duration: time.Second,
+		Total:    10,
+	}
+
+	step := evaluateSearchStep(cfg, 10, res)
+	assert.True(t, step.Passed)
+	assert.Equal(t, 10, step.Total)
+	assert.Equal(t, 0.1, step.ErrorRatio)
+	assert.Equal(t, float64(10), step.AchievedRate)
+
+	step = evaluateSearchStep(cfg, 20, res)
+	assert.False(t, step.Passed)
+	assert.Contains(t, step.Reason, "achieved rate")
+}
@@ -0,0 +1,101 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package request
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Azure/kperf/metrics"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+)
+
+// tracerName is the instrumentation name of kperf's spans.
+const tracerName = "github.com/Azure/kperf/request"
+
+// startRequestSpan starts client span for the request. The span is no-op
+// unless global TracerProvider has been configured.
+func startRequestSpan(ctx context.Context, info metrics.RequestInfo) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx,
+		fmt.Sprintf("%s %s", info.Verb, info.Resource),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("kperf.tag", info.Tag),
+			attribute.String("kperf.verb", info.Verb),
+			attribute.String("kperf.group", info.Group),
+			attribute.String("kperf.resource", info.Resource),
+			attribute.String("kperf.scope", info.Scope),
+			attribute.String("url.full", info.URL),
+		),
+	)
+}
+
+// endRequestSpan records the result of request and ends the span.
+func endRequestSpan(span trace.Span, bytes int64, retries int, err error) {
+	defer span.End()
+
+	if !span.IsRecording() {
+		return
+	}
+
+	span.SetAttributes(
+		attribute.Int64("kperf.received_bytes", bytes),
+		attribute.Int("kperf.retries", retries),
+	)
+
+	if err == nil {
+		span.SetAttributes(attribute.Int("http.response.status_code", http.StatusOK))
+		return
+	}
+
+	var status apierrors.APIStatus
+	if errors.As(err, &status) {
+		span.SetAttributes(attribute.Int("http.response.status_code", int(status.Status().Code)))
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// traceContextRoundTripper propagates trace context carried by request's
+// context to kube-apiserver, for instance, W3C traceparent header.
+type traceContextRoundTripper struct {
+	rt http.RoundTripper
+}
+
+var _ utilnet.RoundTripperWrapper = &traceContextRoundTripper{}
+
+// newTraceContextRoundTripper wraps http.RoundTripper.
+func newTraceContextRoundTripper(rt http.RoundTripper) http.RoundTripper {
+	return &traceContextRoundTripper{rt: rt}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (rt *traceContextRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	carrier := propagation.HeaderCarrier{}
+	otel.GetTextMapPropagator().Inject(req.Context(), carrier)
+	if len(carrier) == 0 {
+		return rt.rt.RoundTrip(req)
+	}
+
+	// NOTE: RoundTripper should not modify the request.
+	req = utilnet.CloneRequest(req)
+	for k, v := range carrier {
+		req.Header[k] = v
+	}
+	return rt.rt.RoundTrip(req)
+}
+
+// WrappedRoundTripper implements utilnet.RoundTripperWrapper.
+func (rt *traceContextRoundTripper) WrappedRoundTripper() http.RoundTripper {
+	return rt.rt
+}
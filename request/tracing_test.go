@@ -0,0 +1,82 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package request
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Azure/kperf/metrics"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+func TestTraceContextRoundTripper(t *testing.T) {
+	var traceparent string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	cli := &http.Client{Transport: newTraceContextRoundTripper(http.DefaultTransport)}
+	info := metrics.RequestInfo{URL: srv.URL, Verb: "LIST", Resource: "pods"}
+
+	send := func(ctx context.Context) {
+		req, err := http.NewRequestWithContext(ctx, "GET", srv.URL, nil)
+		require.NoError(t, err)
+
+		resp, err := cli.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		assert.Empty(t, req.Header.Get("traceparent"), "should not modify request")
+	}
+
+	// no-op by default
+	ctx, span := startRequestSpan(context.Background(), info)
+	send(ctx)
+	endRequestSpan(span, 0, 0, nil)
+	assert.Empty(t, traceparent)
+
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	oldTP, oldPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer func() {
+		otel.SetTracerProvider(oldTP)
+		otel.SetTextMapPropagator(oldPropagator)
+	}()
+
+	ctx, span = startRequestSpan(context.Background(), info)
+	send(ctx)
+	endRequestSpan(span, 100, 2, apierrors.NewTooManyRequestsError("retry it later"))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Contains(t, traceparent, spans[0].SpanContext().TraceID().String())
+	assert.Equal(t, "LIST pods", spans[0].Name())
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, kv := range spans[0].Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	assert.Equal(t, "pods", attrs["kperf.resource"].AsString())
+	assert.Equal(t, int64(100), attrs["kperf.received_bytes"].AsInt64())
+	assert.Equal(t, int64(2), attrs["kperf.retries"].AsInt64())
+	assert.Equal(t, int64(http.StatusTooManyRequests), attrs["http.response.status_code"].AsInt64())
+}
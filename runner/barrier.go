@@ -0,0 +1,97 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package runner
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Azure/kperf/api/types"
+)
+
+const (
+	// startBarrierTimeout is the time to wait for the rest of runners after
+	// the first runner arrives. The barrier is released anyway after that.
+	startBarrierTimeout = 5 * time.Minute
+	// startBarrierLead is added to the release time so that all the
+	// runners can receive the response before the common start time.
+	startBarrierLead = 2 * time.Second
+)
+
+// startBarrier blocks runners until all the runners are ready or timeout, so
+// that runners start at the same wall-clock time.
+type startBarrier struct {
+	mu        sync.Mutex
+	expected  int
+	timeout   time.Duration
+	arrived   map[string]struct{}
+	timer     *time.Timer
+	startTime *time.Time
+	releaseCh chan struct{}
+}
+
+func newStartBarrier(expected int, timeout time.Duration) *startBarrier {
+	return &startBarrier{
+		expected:  expected,
+		timeout:   timeout,
+		arrived:   map[string]struct{}{},
+		releaseCh: make(chan struct{}),
+	}
+}
+
+// arrive records that runner is ready and returns the channel which is closed
+// after barrier is released. It's idempotent for the same runner.
+func (b *startBarrier) arrive(runnerName string) <-chan struct{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.startTime != nil {
+		return b.releaseCh
+	}
+
+	b.arrived[runnerName] = struct{}{}
+	if b.timer == nil {
+		b.timer = time.AfterFunc(b.timeout, b.release)
+	}
+	if len(b.arrived) >= b.expected {
+		b.releaseLocked()
+	}
+	return b.releaseCh
+}
+
+// release releases barrier no matter how many runners have arrived.
+func (b *startBarrier) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.releaseLocked()
+}
+
+// releaseLocked releases barrier if it's not released yet.
+//
+// NOTE: The caller should hold the lock.
+func (b *startBarrier) releaseLocked() {
+	if b.startTime != nil {
+		return
+	}
+
+	startTime := time.Now().Add(startBarrierLead).UTC()
+	b.startTime = &startTime
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+	close(b.releaseCh)
+}
+
+// state returns current state of barrier.
+func (b *startBarrier) state() types.RunnerGroupStartBarrier {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return types.RunnerGroupStartBarrier{
+		Expected:  b.expected,
+		Arrived:   len(b.arrived),
+		StartTime: b.startTime,
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
 	"time"
 
@@ -17,6 +18,7 @@ import (
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
 	apitypes "k8s.io/apimachinery/pkg/types"
@@ -33,6 +35,10 @@ var (
 	errRetryable = errors.New("retry")
 )
 
+// runnerTerminationGracePeriod is the seconds for runner to drain in-flight
+// requests and upload partial report after receiving SIGTERM.
+const runnerTerminationGracePeriod = int64(60)
+
 // Handler is to run a set of runners with same load profile.
 type Handler struct {
 	name      string
@@ -74,6 +80,11 @@ func (h *Handler) Name() string {
 	return h.name
 }
 
+// Spec returns RunnerGroup's spec.
+func (h *Handler) Spec() *types.RunnerGroupSpec {
+	return h.spec
+}
+
 // Info returns RunnerGroup information with status.
 func (h *Handler) Info(ctx context.Context) *types.RunnerGroup {
 	rg := &types.RunnerGroup{
@@ -92,7 +103,9 @@ func (h *Handler) Info(ctx context.Context) *types.RunnerGroup {
 	}
 
 	state := types.RunnerGroupStatusStateRunning
-	if jobFinished(job) {
+	if jobSuspended(job) {
+		state = types.RunnerGroupStatusStateAborted
+	} else if jobFinished(job) {
 		state = types.RunnerGroupStatusStateFinished
 	} else if job.Status.StartTime == nil {
 		state = types.RunnerGroupStatusStateUnknown
@@ -105,12 +118,14 @@ func (h *Handler) Info(ctx context.Context) *types.RunnerGroup {
 	return rg
 }
 
-// Deploy deploys a group of runners.
-func (h *Handler) Deploy(ctx context.Context, uploadURL string) error {
+// Deploy deploys a group of runners. The runners upload report to uploadURL,
+// push interim snapshots to interimURL during benchmark and wait for each
+// other at startBarrierURL before benchmark.
+func (h *Handler) Deploy(ctx context.Context, uploadURL, interimURL, startBarrierURL string) error {
 	if err := h.uploadLoadProfileAsConfigMap(ctx); err != nil {
 		return fmt.Errorf("failed to ensure if load profile has been uploaded: %w", err)
 	}
-	return h.deployRunners(ctx, uploadURL)
+	return h.deployRunners(ctx, uploadURL, interimURL, startBarrierURL)
 }
 
 // configMapDataKeyLoadProfile is load profile's name in configmap.
@@ -155,13 +170,13 @@ func (h *Handler) uploadLoadProfileAsConfigMap(ctx context.Context) error {
 }
 
 // deployRunners deploys a group of runners as batch job.
-func (h *Handler) deployRunners(ctx context.Context, uploadURL string) error {
+func (h *Handler) deployRunners(ctx context.Context, uploadURL, interimURL, startBarrierURL string) error {
 	cli := h.clientset.BatchV1().Jobs(h.namespace)
 
 	_, err := cli.Get(ctx, h.name, metav1.GetOptions{})
 	if err != nil {
 		if apierrors.IsNotFound(err) {
-			_, err = cli.Create(ctx, h.buildBatchJobObject(uploadURL), metav1.CreateOptions{})
+			_, err = cli.Create(ctx, h.buildBatchJobObject(uploadURL, interimURL, startBarrierURL), metav1.CreateOptions{})
 		}
 		return err
 	}
@@ -171,14 +186,7 @@ func (h *Handler) deployRunners(ctx context.Context, uploadURL string) error {
 
 // Pods returns all the pods controlled by the job.
 func (h *Handler) Pods(ctx context.Context) ([]*corev1.Pod, error) {
-	selector, err := metav1.LabelSelectorAsSelector(
-		&metav1.LabelSelector{
-			MatchLabels: map[string]string{
-				"batch.kubernetes.io/job-name": h.name,
-				"job-name":                     h.name,
-			},
-		},
-	)
+	selector, err := metav1.LabelSelectorAsSelector(h.podLabelSelector())
 	if err != nil {
 		return nil, fmt.Errorf("failed to create label selector: %w", err)
 	}
@@ -206,6 +214,16 @@ func (h *Handler) Pods(ctx context.Context) ([]*corev1.Pod, error) {
 	return res, nil
 }
 
+// podLabelSelector returns the selector matching pods created by the job.
+func (h *Handler) podLabelSelector() *metav1.LabelSelector {
+	return &metav1.LabelSelector{
+		MatchLabels: map[string]string{
+			"batch.kubernetes.io/job-name": h.name,
+			"job-name":                     h.name,
+		},
+	}
+}
+
 // IsControlled returns true if the pod is controlled by the group.
 func (h *Handler) IsControlled(ctx context.Context, podName string) (bool, error) {
 	// Fast path: job's name will be the prefix of pod's name.
@@ -236,8 +254,8 @@ func (h *Handler) Wait(ctx context.Context) error {
 			h.name, h.namespace, err)
 	}
 
-	if jobFinished(job) {
-		return nil
+	if jobFinished(job) || jobSuspended(job) {
+		return h.waitForRunnersTerminated(ctx)
 	}
 
 	// NOTE: It's to align with client-go package. Please check out the
@@ -301,10 +319,42 @@ func (h *Handler) Wait(ctx context.Context) error {
 				return err
 			}
 		}
-		return nil
+		return h.waitForRunnersTerminated(ctx)
 	}
 }
 
+// Abort suspends the job so that runners receive SIGTERM and upload partial
+// reports before exit. The job won't create new runners after that.
+func (h *Handler) Abort(ctx context.Context) error {
+	cli := h.clientset.BatchV1().Jobs(h.namespace)
+
+	patch := []byte(`{"spec":{"suspend":true}}`)
+	_, err := cli.Patch(ctx, h.name, apitypes.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to suspend job %s: %w", h.name, err)
+	}
+	return nil
+}
+
+// waitForRunnersTerminated waits until there is no pending or running runner.
+func (h *Handler) waitForRunnersTerminated(ctx context.Context) error {
+	return wait.PollUntilContextCancel(ctx, 5*time.Second, true, func(ctx context.Context) (bool, error) {
+		pods, err := h.Pods(ctx)
+		if err != nil {
+			klog.V(2).ErrorS(err, "failed to list runners", "runner-group", h.name)
+			return false, nil
+		}
+
+		for _, pod := range pods {
+			switch pod.Status.Phase {
+			case corev1.PodPending, corev1.PodRunning:
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+}
+
 // waitForJob will return if job finish.
 func (h *Handler) waitForJob(ctx context.Context, w watch.Interface, rv *string) error {
 	defer w.Stop()
@@ -337,7 +387,7 @@ func (h *Handler) waitForJob(ctx context.Context, w watch.Interface, rv *string)
 				klog.V(5).Infof("Job %s Expected %v, Failed %v, Successed: %v",
 					job.Name, *job.Spec.Completions, job.Status.Failed, job.Status.Succeeded)
 
-				if jobFinished(job) {
+				if jobFinished(job) || jobSuspended(job) {
 					return nil
 				}
 			default:
@@ -349,7 +399,7 @@ func (h *Handler) waitForJob(ctx context.Context, w watch.Interface, rv *string)
 }
 
 // buildBatchJobObject builds job object to run runners.
-func (h *Handler) buildBatchJobObject(uploadURL string) *batchv1.Job {
+func (h *Handler) buildBatchJobObject(uploadURL, interimURL, startBarrierURL string) *batchv1.Job {
 	job := &batchv1.Job{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      h.name,
@@ -361,7 +411,6 @@ func (h *Handler) buildBatchJobObject(uploadURL string) *batchv1.Job {
 			BackoffLimit: toPtr(int32(0)),
 			// FIXME: Should not re-create pod
 			CompletionMode: toPtr(batchv1.IndexedCompletion),
-			Template:       corev1.PodTemplateSpec{},
 		},
 	}
 
@@ -370,7 +419,8 @@ func (h *Handler) buildBatchJobObject(uploadURL string) *batchv1.Job {
 	}
 
 	job.Spec.Template.Spec = corev1.PodSpec{
-		Affinity: &corev1.Affinity{},
+		Affinity:                      &corev1.Affinity{},
+		TerminationGracePeriodSeconds: toPtr(runnerTerminationGracePeriod),
 		Containers: []corev1.Container{
 			{
 				Name:  "runner",
@@ -400,10 +450,6 @@ func (h *Handler) buildBatchJobObject(uploadURL string) *batchv1.Job {
 							},
 						},
 					},
-					{
-						Name:  "TARGET_URL",
-						Value: uploadURL,
-					},
 				},
 				VolumeMounts: []corev1.VolumeMount{
 					{
@@ -415,8 +461,20 @@ func (h *Handler) buildBatchJobObject(uploadURL string) *batchv1.Job {
 						MountPath: "/data",
 					},
 				},
+				// NOTE: The $(VAR) references are expanded by kubelet.
 				Command: []string{
-					"/run_runner.sh",
+					"/kperf",
+					"-v=2",
+					"runner",
+					"run",
+					"--config=/config/load_profile.yaml",
+					"--user-agent=$(POD_NAME)",
+					"--result=/data/$(POD_NAMESPACE)-$(POD_NAME)-$(POD_UID).json",
+					"--raw-data",
+					"--fail-on-slo-violation=false",
+					"--start-barrier-url=" + startBarrierURL,
+					"--upload-url=" + uploadURL,
+					"--interim-upload-url=" + interimURL,
 				},
 			},
 		},
@@ -468,9 +526,133 @@ func (h *Handler) buildBatchJobObject(uploadURL string) *batchv1.Job {
 		job.Spec.Template.Spec.ServiceAccountName = *sa
 	}
 
+	if spread := h.spec.Spread; spread != nil {
+		h.applySpread(&job.Spec.Template.Spec, spread)
+	}
+
+	if overrides := h.spec.PodOverrides; overrides != nil {
+		applyPodOverrides(&job.Spec.Template.Spec, overrides)
+	}
+
+	if m := h.spec.Metrics; m != nil {
+		applyMetrics(&job.Spec.Template, m)
+	}
 	return job
 }
 
+// applyMetrics lets runner expose Prometheus metrics on the port, with scrape
+// annotations if required.
+func applyMetrics(tmpl *corev1.PodTemplateSpec, m *types.RunnerMetrics) {
+	port := m.Port
+	if port == 0 {
+		port = types.DefaultRunnerMetricsPort
+	}
+
+	container := &tmpl.Spec.Containers[0]
+	container.Command = append(container.Command, fmt.Sprintf("--metrics-addr=:%d", port))
+	container.Ports = append(container.Ports, corev1.ContainerPort{
+		Name:          "metrics",
+		ContainerPort: port,
+		Protocol:      corev1.ProtocolTCP,
+	})
+
+	if m.ScrapeAnnotations {
+		if tmpl.Annotations == nil {
+			tmpl.Annotations = map[string]string{}
+		}
+		tmpl.Annotations["prometheus.io/scrape"] = "true"
+		tmpl.Annotations["prometheus.io/port"] = strconv.Itoa(int(port))
+		tmpl.Annotations["prometheus.io/path"] = "/metrics"
+	}
+}
+
+// applySpread renders RunnerSpread into pod anti-affinity and topology
+// spread constraints.
+func (h *Handler) applySpread(podSpec *corev1.PodSpec, spread *types.RunnerSpread) {
+	if spread.OnePerNode {
+		podSpec.Affinity.PodAntiAffinity = &corev1.PodAntiAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{
+				{
+					LabelSelector: h.podLabelSelector(),
+					TopologyKey:   corev1.LabelHostname,
+				},
+			},
+		}
+	}
+
+	for _, c := range spread.TopologyConstraints {
+		maxSkew := c.MaxSkew
+		if maxSkew == 0 {
+			maxSkew = 1
+		}
+
+		whenUnsatisfiable := corev1.DoNotSchedule
+		if c.WhenUnsatisfiable != "" {
+			whenUnsatisfiable = corev1.UnsatisfiableConstraintAction(c.WhenUnsatisfiable)
+		}
+
+		podSpec.TopologySpreadConstraints = append(podSpec.TopologySpreadConstraints, corev1.TopologySpreadConstraint{
+			MaxSkew:           maxSkew,
+			TopologyKey:       c.TopologyKey,
+			WhenUnsatisfiable: whenUnsatisfiable,
+			LabelSelector:     h.podLabelSelector(),
+		})
+	}
+}
+
+// applyPodOverrides applies RunnerPodOverrides to runner's pod spec. The
+// overrides should have been validated.
+func applyPodOverrides(podSpec *corev1.PodSpec, overrides *types.RunnerPodOverrides) {
+	if res := overrides.Resources; res != nil {
+		podSpec.Containers[0].Resources = corev1.ResourceRequirements{
+			Requests: toResourceList(res.Requests),
+			Limits:   toResourceList(res.Limits),
+		}
+	}
+
+	for _, t := range overrides.Tolerations {
+		podSpec.Tolerations = append(podSpec.Tolerations, corev1.Toleration{
+			Key:               t.Key,
+			Operator:          corev1.TolerationOperator(t.Operator),
+			Value:             t.Value,
+			Effect:            corev1.TaintEffect(t.Effect),
+			TolerationSeconds: t.TolerationSeconds,
+		})
+	}
+
+	podSpec.PriorityClassName = overrides.PriorityClassName
+
+	for _, name := range overrides.ImagePullSecrets {
+		podSpec.ImagePullSecrets = append(podSpec.ImagePullSecrets, corev1.LocalObjectReference{
+			Name: name,
+		})
+	}
+
+	if overrides.DataVolume == types.RunnerDataVolumeEmptyDir {
+		for idx := range podSpec.Volumes {
+			if podSpec.Volumes[idx].Name == "host-root-tmp" {
+				podSpec.Volumes[idx].VolumeSource = corev1.VolumeSource{
+					EmptyDir: &corev1.EmptyDirVolumeSource{},
+				}
+			}
+		}
+	}
+}
+
+// toResourceList converts {"cpu": "2"} into corev1.ResourceList. It returns
+// nil if the list is empty.
+func toResourceList(list map[string]string) corev1.ResourceList {
+	if len(list) == 0 {
+		return nil
+	}
+
+	res := make(corev1.ResourceList, len(list))
+	for k, v := range list {
+		res[corev1.ResourceName(k)] = resource.MustParse(v)
+	}
+	return res
+}
+
 func buildOwnerReference(ref *string) (*metav1.OwnerReference, error) {
 	if ref == nil {
 		return nil, nil
@@ -494,6 +676,11 @@ func jobFinished(job *batchv1.Job) bool {
 	return job.Status.Failed+job.Status.Succeeded == *job.Spec.Completions
 }
 
+// jobSuspended returns true if runner group has been aborted.
+func jobSuspended(job *batchv1.Job) bool {
+	return job.Spec.Suspend != nil && *job.Spec.Suspend
+}
+
 func toPtr[T any](v T) *T {
 	return &v
 }
@@ -87,6 +87,21 @@ func (s *Store) OpenReader(ref string) (Reader, error) {
 	}, nil
 }
 
+// Exists returns true if committed content named by ref exists.
+func (s *Store) Exists(ref string) (bool, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	_, err := os.Stat(filepath.Join(s.dataDir, ref))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to ensure if ref %s exists: %w", ref, err)
+	}
+	return true, nil
+}
+
 // Delete is to delete committed content named by ref.
 func (s *Store) Delete(ref string) error {
 	s.Lock()
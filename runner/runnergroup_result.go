@@ -13,8 +13,10 @@ import (
 	"github.com/Azure/kperf/api/types"
 )
 
-// GetRunnerGroupResult gets runner group's aggregated report.
-func GetRunnerGroupResult(ctx context.Context, kubecfgPath string, wait bool) (*types.RunnerGroupsReport, error) {
+// GetRunnerGroupResult gets runner group's aggregated report. If partial is
+// true and runner groups are still running, it returns the summary merged
+// from the reports uploaded so far without waiting.
+func GetRunnerGroupResult(ctx context.Context, kubecfgPath string, wait, partial bool) (*types.RunnerGroupsReport, error) {
 	host, done, err := initPortForwardToServer(kubecfgPath)
 	if err != nil {
 		return nil, err
@@ -22,7 +24,9 @@ func GetRunnerGroupResult(ctx context.Context, kubecfgPath string, wait bool) (*
 	defer done()
 
 	targetURL := fmt.Sprintf("http://%s/v1/runnergroups/summary", host)
-	if wait {
+	if partial {
+		targetURL += "?partial=true"
+	} else if wait {
 		targetURL += "?wait=true"
 	}
 
@@ -66,3 +70,12 @@ func GetRunnerGroupResult(ctx context.Context, kubecfgPath string, wait bool) (*
 	}
 	return &res, nil
 }
+
+// ValidateRunnerGroupSLO verifies that SLO can be evaluated against runner
+// groups' summary.
+//
+// NOTE: The runners don't record latencies by URL. Please update it if
+// group.Handler passes --latencies-by-url to runners.
+func ValidateRunnerGroupSLO(slo *types.SLO) error {
+	return slo.ValidateLatenciesByURL(false)
+}
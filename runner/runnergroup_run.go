@@ -28,6 +28,7 @@ var (
 )
 
 // CreateRunnerGroupServer creates a long running server to deploy runner groups.
+// All the runner groups run concurrently.
 //
 // TODO:
 // 1. create a new package to define ErrNotFound, ErrAlreadyExists, ... errors.
@@ -35,10 +36,14 @@ var (
 func CreateRunnerGroupServer(ctx context.Context,
 	kubeconfigPath string,
 	runnerImage string,
-	rgSpec *types.RunnerGroupSpec,
+	rgSpecs []*types.RunnerGroupSpec,
 	opts ...RunCmdOpt,
 ) error {
-	specInStr, err := tweakAndMarshalSpec(rgSpec)
+	if len(rgSpecs) == 0 {
+		return fmt.Errorf("required at least one runner group spec")
+	}
+
+	specsAppiler, err := tweakAndMarshalSpecs(rgSpecs)
 	if err != nil {
 		return err
 	}
@@ -77,8 +82,8 @@ func CreateRunnerGroupServer(ctx context.Context,
 		helmcli.StringPathValuesApplier(
 			"name="+runnerGroupServerReleaseName,
 			"image="+runnerImage,
-			"runnerGroupSpec="+specInStr,
 		),
+		specsAppiler,
 		appiler,
 	)
 	if err != nil {
@@ -103,6 +108,33 @@ func tweakAndMarshalSpec(spec *types.RunnerGroupSpec) (string, error) {
 	return string(data), nil
 }
 
+// tweakAndMarshalSpecs marshals specs into runnerGroupSpecs value.
+//
+// NOTE: It should be aligned with ../manifests/runnergroup/server/values.yaml.
+func tweakAndMarshalSpecs(specs []*types.RunnerGroupSpec) (helmcli.ValuesApplier, error) {
+	specsInStr := make([]string, 0, len(specs))
+	for idx, spec := range specs {
+		specInStr, err := tweakAndMarshalSpec(spec)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal runner group spec #%d: %w", idx, err)
+		}
+		specsInStr = append(specsInStr, specInStr)
+	}
+
+	rawData, err := yaml.Marshal(map[string]interface{}{
+		"runnerGroupSpecs": specsInStr,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render runner group specs into YAML: %w", err)
+	}
+
+	appiler, err := helmcli.YAMLValuesApplier(string(rawData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare value appiler for runner group specs: %w", err)
+	}
+	return appiler, nil
+}
+
 type runCmdConfig struct {
 	// serverNodeSelectors forces to schedule server to nodes with that specific labels.
 	serverNodeSelectors map[string][]string
@@ -115,6 +147,8 @@ type runCmdConfig struct {
 		priorityLevel      string
 		matchingPrecedence int
 	}
+	// apiserverMetrics enables server to scrape kube-apiserver's metrics.
+	apiserverMetrics bool
 
 	// TODO(weifu): merge name/image/specs into this
 }
@@ -137,6 +171,14 @@ func WithRunCmdRunnerGroupFlowControl(priorityLevel string, matchingPrecedence i
 	}
 }
 
+// WithRunCmdAPIServerMetricsOpt enables server to show the change of
+// kube-apiserver's metrics in summary.
+func WithRunCmdAPIServerMetricsOpt(enabled bool) RunCmdOpt {
+	return func(cfg *runCmdConfig) {
+		cfg.apiserverMetrics = enabled
+	}
+}
+
 // toServerHelmValuesAppiler creates ValuesApplier.
 //
 // NOTE: It should be aligned with ../manifests/runnergroup/server/values.yaml.
@@ -147,6 +189,7 @@ func (cfg *runCmdConfig) toServerHelmValuesAppiler() (helmcli.ValuesApplier, err
 			"priorityLevelConfiguration": cfg.runnerGroupFlowcontrol.priorityLevel,
 			"matchingPrecedence":         cfg.runnerGroupFlowcontrol.matchingPrecedence,
 		},
+		"apiserverMetrics": cfg.apiserverMetrics,
 	}
 
 	rawData, err := yaml.Marshal(values)
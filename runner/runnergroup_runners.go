@@ -0,0 +1,185 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/Azure/kperf/api/types"
+
+	"k8s.io/klog/v2"
+)
+
+// GetRunnerReport writes runner's raw report into w. The runner is looked up
+// in all the runner groups.
+func GetRunnerReport(ctx context.Context, kubeCfgPath string, runnerName string, w io.Writer) error {
+	host, done, err := initPortForwardToServer(kubeCfgPath)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	groups := []*types.RunnerGroup{}
+	if err := getFromServer(ctx, fmt.Sprintf("http://%s/v1/runnergroups", host), &groups); err != nil {
+		return err
+	}
+
+	for _, g := range groups {
+		runners, err := listRunnerGroupRunners(ctx, host, g.Name)
+		if err != nil {
+			return err
+		}
+
+		for _, r := range runners {
+			if r.Name != runnerName {
+				continue
+			}
+			if !r.HasReport {
+				return fmt.Errorf("runner %s in runner group %s hasn't uploaded report (state: %s)",
+					runnerName, g.Name, r.State)
+			}
+			return downloadRunnerReport(ctx, host, g.Name, runnerName, w)
+		}
+	}
+	return fmt.Errorf("no such runner %s", runnerName)
+}
+
+// DownloadRunnerReports downloads all the runners' raw reports into
+// outputDir/<runner group>/<runner>.json. The runners without report are
+// skipped.
+func DownloadRunnerReports(ctx context.Context, kubeCfgPath string, outputDir string) error {
+	host, done, err := initPortForwardToServer(kubeCfgPath)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	groups := []*types.RunnerGroup{}
+	if err := getFromServer(ctx, fmt.Sprintf("http://%s/v1/runnergroups", host), &groups); err != nil {
+		return err
+	}
+
+	for _, g := range groups {
+		runners, err := listRunnerGroupRunners(ctx, host, g.Name)
+		if err != nil {
+			return err
+		}
+
+		groupDir := filepath.Join(outputDir, g.Name)
+		if err := os.MkdirAll(groupDir, 0750); err != nil {
+			return fmt.Errorf("failed to ensure output's dir %s: %w", groupDir, err)
+		}
+
+		for _, r := range runners {
+			if !r.HasReport {
+				klog.Warningf("Skip runner %s in runner group %s without report (state: %s)",
+					r.Name, g.Name, r.State)
+				continue
+			}
+
+			if err := downloadRunnerReportToFile(ctx, host, g.Name, r.Name,
+				filepath.Join(groupDir, r.Name+".json")); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// listRunnerGroupRunners lists runners in runner group.
+func listRunnerGroupRunners(ctx context.Context, host string, groupName string) ([]types.RunnerReportStatus, error) {
+	targetURL := fmt.Sprintf("http://%s/v1/runnergroups/%s/runners", host, groupName)
+
+	res := []types.RunnerReportStatus{}
+	if err := getFromServer(ctx, targetURL, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// downloadRunnerReportToFile downloads runner's raw report into target file.
+func downloadRunnerReportToFile(ctx context.Context, host string, groupName, runnerName string, target string) error {
+	f, err := os.Create(target)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", target, err)
+	}
+	defer f.Close()
+
+	return downloadRunnerReport(ctx, host, groupName, runnerName, f)
+}
+
+// downloadRunnerReport writes runner's raw report into w.
+func downloadRunnerReport(ctx context.Context, host string, groupName, runnerName string, w io.Writer) error {
+	targetURL := fmt.Sprintf("http://%s/v1/runnergroups/%s/runners/%s/result", host, groupName, runnerName)
+
+	resp, err := doGetFromServer(ctx, targetURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("failed to download report of runner %s: %w", runnerName, err)
+	}
+	return nil
+}
+
+// getFromServer sends GET request to targetURL and unmarshals response into v.
+func getFromServer(ctx context.Context, targetURL string, v interface{}) error {
+	resp, err := doGetFromServer(ctx, targetURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	dataInRaw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read data: %w", err)
+	}
+
+	if err := json.Unmarshal(dataInRaw, v); err != nil {
+		return fmt.Errorf("failed to unmarshal response from %s: %w\n\n%s",
+			targetURL, err, string(dataInRaw))
+	}
+	return nil
+}
+
+// doGetFromServer sends GET request to targetURL. It returns error if the
+// http code isn't 200. The caller should close response's body.
+func doGetFromServer(ctx context.Context, targetURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", targetURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init GET request: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to access %s by portforward: %w", targetURL, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+
+		errInRaw, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read error message when http code = %v: %w",
+				resp.Status, err)
+		}
+
+		herr := types.HTTPError{}
+		err = json.Unmarshal(errInRaw, &herr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get error when http code = %v: %w",
+				resp.Status, err)
+		}
+		return nil, herr
+	}
+	return resp, nil
+}
@@ -0,0 +1,75 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/Azure/kperf/api/types"
+)
+
+// StopRunnerGroups aborts runner groups by name. All the runner groups are
+// aborted if names is empty. The runners upload partial reports and the
+// summary is still available after that.
+func StopRunnerGroups(ctx context.Context, kubeCfgPath string, names []string) error {
+	if len(names) == 0 {
+		groups, err := ListRunnerGroups(ctx, kubeCfgPath)
+		if err != nil {
+			return err
+		}
+		for _, g := range groups {
+			names = append(names, g.Name)
+		}
+	}
+
+	host, done, err := initPortForwardToServer(kubeCfgPath)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	for _, name := range names {
+		if err := stopRunnerGroup(ctx, host, name); err != nil {
+			return fmt.Errorf("failed to stop runner group %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// stopRunnerGroup aborts runner group by name.
+func stopRunnerGroup(ctx context.Context, host string, name string) error {
+	targetURL := fmt.Sprintf("http://%s/v1/runnergroups/%s", host, name)
+
+	req, err := http.NewRequestWithContext(ctx, "DELETE", targetURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to init DELETE request: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to access %s by portforward: %w", targetURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		errInRaw, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read error message when http code = %v: %w",
+				resp.Status, err)
+		}
+
+		herr := types.HTTPError{}
+		err = json.Unmarshal(errInRaw, &herr)
+		if err != nil {
+			return fmt.Errorf("failed to get error when http code = %v: %w",
+				resp.Status, err)
+		}
+		return herr
+	}
+	return nil
+}
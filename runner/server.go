@@ -4,19 +4,28 @@
 package runner
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"net"
 	"net/http"
+	"sort"
+	"strconv"
 	"sync"
 
 	"github.com/Azure/kperf/api/types"
+	"github.com/Azure/kperf/metrics"
+	"github.com/Azure/kperf/request"
 	"github.com/Azure/kperf/runner/group"
 	"github.com/Azure/kperf/runner/localstore"
 
 	"github.com/gorilla/mux"
+	"k8s.io/klog/v2"
 )
 
 // Server is to deploy runner groups and expose endpoints for runner report.
@@ -25,11 +34,46 @@ type Server struct {
 	listeners []net.Listener
 	groups    []*group.Handler
 	readyCh   chan struct{}
-	report    *types.RunnerMetricReport
+	report    *types.RunnerGroupsReport
+	// states tracks the runner group at the same index in groups.
+	states []*runnerGroupState
+	// barriers stores start barrier for each runner group, keyed by
+	// runner group's name.
+	barriers map[string]*startBarrier
+
+	// flowControl is the flowcontrol setting applied to runners, only used
+	// in summary.
+	flowControl *types.RunnerGroupFlowControl
+	// scraper is nil if kube-apiserver's metrics are not required.
+	scraper *request.APIServerMetricsScraper
+	// apiserverMetricsBefore is the snapshot before deploying runner groups.
+	apiserverMetricsBefore map[string]*metrics.APIServerMetricsSnapshot
+}
+
+// ServerOpt is used to configure Server.
+type ServerOpt func(*Server)
+
+// WithServerAPIServerMetricsScraperOpt scrapes kube-apiserver's metrics
+// before and after runner groups and shows the change in summary.
+func WithServerAPIServerMetricsScraperOpt(scraper *request.APIServerMetricsScraper) ServerOpt {
+	return func(s *Server) {
+		s.scraper = scraper
+	}
+}
+
+// WithServerFlowControlOpt shows the flowcontrol setting applied to runners
+// in summary.
+func WithServerFlowControlOpt(priorityLevel string, matchingPrecedence int) ServerOpt {
+	return func(s *Server) {
+		s.flowControl = &types.RunnerGroupFlowControl{
+			PriorityLevel:      priorityLevel,
+			MatchingPrecedence: matchingPrecedence,
+		}
+	}
 }
 
 // NewServer returns new instance of server.
-func NewServer(dataDir string, addrs []string, groups ...*group.Handler) (*Server, error) {
+func NewServer(dataDir string, addrs []string, groups []*group.Handler, opts ...ServerOpt) (*Server, error) {
 	s, err := localstore.NewStore(dataDir)
 	if err != nil {
 		return nil, err
@@ -40,28 +84,43 @@ func NewServer(dataDir string, addrs []string, groups ...*group.Handler) (*Serve
 		return nil, err
 	}
 
-	return &Server{
+	states, err := newRunnerGroupStates(groups)
+	if err != nil {
+		return nil, err
+	}
+
+	barriers := make(map[string]*startBarrier, len(groups))
+	for _, g := range groups {
+		barriers[g.Name()] = newStartBarrier(int(g.Spec().Count), startBarrierTimeout)
+	}
+
+	srv := &Server{
 		listeners: listeners,
 		groups:    groups,
+		states:    states,
+		barriers:  barriers,
 		store:     s,
 		readyCh:   make(chan struct{}),
-	}, nil
+	}
+	for _, opt := range opts {
+		opt(srv)
+	}
+	return srv, nil
 }
 
 // Run is to expose endpoints.
 func (s *Server) Run() error {
+	if s.scraper != nil {
+		s.apiserverMetricsBefore = s.scraper.Scrape(context.TODO())
+	}
+
 	if err := s.deployRunnerGroups(); err != nil {
 		return fmt.Errorf("failed to deploy runner group %w", err)
 	}
 
 	go s.waitForRunnerGroups()
 
-	r := mux.NewRouter()
-	// NOTE: Please update ./runnergroup_list.go if endpoint has been changed.
-	r.HandleFunc("/v1/runnergroups", s.listRunnerGroupsHandler).Methods("GET")
-	// NOTE: Please update ./runnergroup_result.go if endpoint has been changed.
-	r.HandleFunc("/v1/runnergroups/summary", s.getRunnerGroupsSummary).Methods("GET")
-	r.HandleFunc("/v1/runnergroups/{runner_name}/result", s.postRunnerGroupsRunnerResult).Methods("POST")
+	r := s.router()
 
 	errCh := make(chan error, len(s.listeners))
 	var wg sync.WaitGroup
@@ -83,6 +142,23 @@ func (s *Server) Run() error {
 	return nil
 }
 
+// router returns the handler of server's endpoints.
+func (s *Server) router() *mux.Router {
+	r := mux.NewRouter()
+	// NOTE: Please update ./runnergroup_list.go if endpoint has been changed.
+	r.HandleFunc("/v1/runnergroups", s.listRunnerGroupsHandler).Methods("GET")
+	// NOTE: Please update ./runnergroup_result.go if endpoint has been changed.
+	r.HandleFunc("/v1/runnergroups/summary", s.getRunnerGroupsSummary).Methods("GET")
+	r.HandleFunc("/v1/runnergroups/{runner_name}/result", s.postRunnerGroupsRunnerResult).Methods("POST")
+	r.HandleFunc("/v1/runnergroups/{runner_name}/interim", s.postRunnerGroupsRunnerInterim).Methods("POST")
+	r.HandleFunc("/v1/runnergroups/{runner_group_name}/start", s.getRunnerGroupStartBarrier).Methods("GET")
+	r.HandleFunc("/v1/runnergroups/{runner_group_name}", s.deleteRunnerGroup).Methods("DELETE")
+	// NOTE: Please update ./runnergroup_runners.go if endpoint has been changed.
+	r.HandleFunc("/v1/runnergroups/{runner_group_name}/runners", s.listRunnerGroupRunners).Methods("GET")
+	r.HandleFunc("/v1/runnergroups/{runner_group_name}/runners/{runner_name}/result", s.getRunnerGroupRunnerResult).Methods("GET")
+	return r
+}
+
 // listRunnerGroupsHandler lists all the runner groups.
 func (s *Server) listRunnerGroupsHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -97,13 +173,22 @@ func (s *Server) listRunnerGroupsHandler(w http.ResponseWriter, r *http.Request)
 	_, _ = w.Write(data)
 }
 
-// getRunnerGroupsSummary returns summary report.
+// getRunnerGroupsSummary returns summary report. With partial, it returns
+// best-effort summary merged from the reports uploaded so far if runner
+// groups are still running.
 func (s *Server) getRunnerGroupsSummary(w http.ResponseWriter, r *http.Request) {
 	wait := r.URL.Query().Has("wait")
+	partial := r.URL.Query().Has("partial")
 
 	select {
 	case <-s.readyCh:
 	default:
+		if partial {
+			data, _ := json.Marshal(s.buildPartialSummary())
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write(data)
+			return
+		}
 		if !wait {
 			renderErrorResponse(w, http.StatusNotFound, fmt.Errorf("summary is not ready"))
 			return
@@ -123,25 +208,202 @@ func (s *Server) getRunnerGroupsSummary(w http.ResponseWriter, r *http.Request)
 	_, _ = w.Write(data)
 }
 
-// postRunnerGroupsRunnerResult receives summary result from runner.
-func (s *Server) postRunnerGroupsRunnerResult(w http.ResponseWriter, r *http.Request) {
+// buildPartialSummary merges the reports uploaded so far. The runners which
+// haven't uploaded report are merged by their latest interim snapshots.
+func (s *Server) buildPartialSummary() *types.RunnerGroupsReport {
+	report := buildRunnerGroupSummary(s.store, s.groups, s.states, s.flowControl, true)
+	report.Partial = true
+	for _, g := range report.Groups {
+		for _, runner := range g.Runners {
+			if runner.HasReport || runner.Interim {
+				report.IncludedRunners = append(report.IncludedRunners, runner.Name)
+			}
+		}
+	}
+	sort.Strings(report.IncludedRunners)
+	return report
+}
+
+// getRunnerGroupStartBarrier records that runner is ready and returns the
+// barrier's state. With wait, it blocks until all the runners in that group
+// are ready or timeout.
+func (s *Server) getRunnerGroupStartBarrier(w http.ResponseWriter, r *http.Request) {
+	groupName := mux.Vars(r)["runner_group_name"]
+	runnerName := r.URL.Query().Get("runner")
+	wait := r.URL.Query().Has("wait")
+	ctx := r.Context()
+
+	idx := s.runnerGroupIndex(groupName)
+	if idx == -1 {
+		renderErrorResponse(w, http.StatusNotFound, fmt.Errorf("no such runner group %s", groupName))
+		return
+	}
+	rg := s.groups[idx]
+
+	if runnerName == "" {
+		renderErrorResponse(w, http.StatusBadRequest, fmt.Errorf("required runner query"))
+		return
+	}
+
+	found, err := rg.IsControlled(ctx, runnerName)
+	if err != nil {
+		renderErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !found {
+		renderErrorResponse(w, http.StatusNotFound, fmt.Errorf("no such runner %s in runner group %s", runnerName, groupName))
+		return
+	}
+
+	barrier := s.barriers[groupName]
+	releaseCh := barrier.arrive(runnerName)
+	if wait {
+		select {
+		case <-releaseCh:
+		case <-ctx.Done():
+			renderErrorResponse(w, http.StatusRequestTimeout, fmt.Errorf("request has been canceled"))
+			return
+		}
+	}
+
+	data, _ := json.Marshal(barrier.state())
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(data)
+}
+
+// deleteRunnerGroup aborts runner group. The runners receive SIGTERM and
+// upload partial reports. The summary is still built after that.
+func (s *Server) deleteRunnerGroup(w http.ResponseWriter, r *http.Request) {
+	groupName := mux.Vars(r)["runner_group_name"]
+
+	idx := s.runnerGroupIndex(groupName)
+	if idx == -1 {
+		renderErrorResponse(w, http.StatusNotFound, fmt.Errorf("no such runner group %s", groupName))
+		return
+	}
+
+	g, state := s.groups[idx], s.states[idx]
+	if isClosed(state.finishedCh) && !isClosed(state.abortCh) {
+		renderErrorResponse(w, http.StatusConflict, fmt.Errorf("runner group %s has finished", groupName))
+		return
+	}
+
+	if !state.abort() {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	// NOTE: The runner group which hasn't been deployed will be skipped.
+	if isClosed(state.deployedCh) {
+		if err := g.Abort(r.Context()); err != nil {
+			renderErrorResponse(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	klog.V(2).InfoS("Aborted runner group", "runner-group", groupName)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// listRunnerGroupRunners lists runners in runner group and whether their
+// reports have been uploaded.
+func (s *Server) listRunnerGroupRunners(w http.ResponseWriter, r *http.Request) {
+	groupName := mux.Vars(r)["runner_group_name"]
+
+	idx := s.runnerGroupIndex(groupName)
+	if idx == -1 {
+		renderErrorResponse(w, http.StatusNotFound, fmt.Errorf("no such runner group %s", groupName))
+		return
+	}
+
+	runners := listRunnerStatuses(s.store, s.groups[idx], s.states[idx])
+
+	data, _ := json.Marshal(runners)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(data)
+}
+
+// getRunnerGroupRunnerResult returns runner's raw report as it was uploaded.
+func (s *Server) getRunnerGroupRunnerResult(w http.ResponseWriter, r *http.Request) {
+	groupName := mux.Vars(r)["runner_group_name"]
 	runnerName := mux.Vars(r)["runner_name"]
 	ctx := r.Context()
 
-	var found = false
-	var err error
-	for _, g := range s.groups {
-		found, err = g.IsControlled(ctx, runnerName)
+	idx := s.runnerGroupIndex(groupName)
+	if idx == -1 {
+		renderErrorResponse(w, http.StatusNotFound, fmt.Errorf("no such runner group %s", groupName))
+		return
+	}
+
+	// NOTE: The runner's pod is deleted if runner group has been aborted.
+	found := s.states[idx].hasUploadedRunner(runnerName)
+	if !found {
+		var err error
+		found, err = s.groups[idx].IsControlled(ctx, runnerName)
 		if err != nil {
 			renderErrorResponse(w, http.StatusInternalServerError, err)
 			return
 		}
+	}
+	if !found {
+		renderErrorResponse(w, http.StatusNotFound, fmt.Errorf("no such runner %s in runner group %s", runnerName, groupName))
+		return
+	}
+
+	reader, err := s.store.OpenReader(runnerName)
+	if err != nil {
+		code := http.StatusInternalServerError
+		if errors.Is(err, fs.ErrNotExist) {
+			code = http.StatusNotFound
+			err = fmt.Errorf("runner %s hasn't uploaded report", runnerName)
+		}
+		renderErrorResponse(w, code, err)
+		return
+	}
+	defer reader.Close()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.FormatInt(reader.Size(), 10))
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, reader); err != nil {
+		klog.V(2).ErrorS(err, "failed to send report", "runner", runnerName)
+	}
+}
+
+// runnerGroupIndex returns the index of runner group by name, or -1.
+func (s *Server) runnerGroupIndex(name string) int {
+	for idx, g := range s.groups {
+		if g.Name() == name {
+			return idx
+		}
+	}
+	return -1
+}
+
+// runnerGroupStateOf returns the state of runner group which controls the
+// runner, or nil.
+func (s *Server) runnerGroupStateOf(ctx context.Context, runnerName string) (*runnerGroupState, error) {
+	for idx, g := range s.groups {
+		found, err := g.IsControlled(ctx, runnerName)
+		if err != nil {
+			return nil, err
+		}
 		if found {
-			break
+			return s.states[idx], nil
 		}
 	}
+	return nil, nil
+}
 
-	if !found {
+// postRunnerGroupsRunnerResult receives summary result from runner.
+func (s *Server) postRunnerGroupsRunnerResult(w http.ResponseWriter, r *http.Request) {
+	runnerName := mux.Vars(r)["runner_name"]
+
+	state, err := s.runnerGroupStateOf(r.Context(), runnerName)
+	if err != nil {
+		renderErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+	if state == nil {
 		renderErrorResponse(w, http.StatusNotFound, fmt.Errorf("no such runner %s", runnerName))
 		return
 	}
@@ -153,12 +415,23 @@ func (s *Server) postRunnerGroupsRunnerResult(w http.ResponseWriter, r *http.Req
 	}
 	defer writer.Close()
 
-	_, err = io.Copy(writer, r.Body)
+	hasher := sha256.New()
+	_, err = io.Copy(io.MultiWriter(writer, hasher), r.Body)
 	if err != nil {
 		renderErrorResponse(w, http.StatusInternalServerError, err)
 		return
 	}
 
+	// NOTE: The data might be corrupted in transit. The runner resends
+	// report on 422.
+	if expected := r.Header.Get(types.RunnerReportChecksumHeader); expected != "" {
+		if got := hex.EncodeToString(hasher.Sum(nil)); got != expected {
+			renderErrorResponse(w, http.StatusUnprocessableEntity,
+				fmt.Errorf("checksum mismatch: expected %s, got %s", expected, got))
+			return
+		}
+	}
+
 	err = writer.Commit(runnerName)
 	if err != nil {
 		code := http.StatusInternalServerError
@@ -168,5 +441,52 @@ func (s *Server) postRunnerGroupsRunnerResult(w http.ResponseWriter, r *http.Req
 		renderErrorResponse(w, code, err)
 		return
 	}
+	state.addUploadedRunner(runnerName)
+	w.WriteHeader(http.StatusCreated)
+}
+
+// postRunnerGroupsRunnerInterim receives runner's interim snapshot during
+// benchmark. It replaces the previous one and is only used by partial summary
+// until runner uploads report.
+func (s *Server) postRunnerGroupsRunnerInterim(w http.ResponseWriter, r *http.Request) {
+	runnerName := mux.Vars(r)["runner_name"]
+
+	state, err := s.runnerGroupStateOf(r.Context(), runnerName)
+	if err != nil {
+		renderErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+	if state == nil {
+		renderErrorResponse(w, http.StatusNotFound, fmt.Errorf("no such runner %s", runnerName))
+		return
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		renderErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if expected := r.Header.Get(types.RunnerReportChecksumHeader); expected != "" {
+		sum := sha256.Sum256(data)
+		if got := hex.EncodeToString(sum[:]); got != expected {
+			renderErrorResponse(w, http.StatusUnprocessableEntity,
+				fmt.Errorf("checksum mismatch: expected %s, got %s", expected, got))
+			return
+		}
+	}
+
+	report := &types.RunnerMetricReport{}
+	if err := json.Unmarshal(data, report); err != nil {
+		renderErrorResponse(w, http.StatusBadRequest, fmt.Errorf("failed to unmarshal: %w", err))
+		return
+	}
+
+	// NOTE: The interim snapshot is stale once report has been uploaded.
+	if state.hasUploadedRunner(runnerName) {
+		renderErrorResponse(w, http.StatusConflict, fmt.Errorf("runner %s has uploaded report", runnerName))
+		return
+	}
+	state.setInterimReport(runnerName, report)
 	w.WriteHeader(http.StatusCreated)
 }
@@ -7,9 +7,161 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
+
+	"github.com/Azure/kperf/api/types"
+	"github.com/Azure/kperf/request"
+	"github.com/Azure/kperf/runner/group"
+
+	"k8s.io/klog/v2"
 )
 
-// deployRunnerGroups deploys runner groups.
+// runnerGroupState tracks runner group's lifecycle so that other runner
+// groups can start after it.
+type runnerGroupState struct {
+	// deployedCh is closed after runners have been deployed.
+	deployedCh chan struct{}
+	// finishedCh is closed after runners finished or runner group was
+	// skipped.
+	finishedCh chan struct{}
+	// abortCh is closed when runner group is aborted.
+	abortCh   chan struct{}
+	abortOnce sync.Once
+	// dependency is the index of runner group in StartAfter, or -1.
+	dependency int
+
+	mu sync.Mutex
+	// skipped is true if runner group wasn't deployed because of failure or
+	// abort.
+	skipped bool
+	// uploaded stores the runners which have uploaded report. The runners'
+	// pods might have been deleted if runner group was aborted.
+	uploaded map[string]struct{}
+	// interims stores runners' latest interim snapshot, keyed by runner's
+	// name.
+	interims map[string]*types.RunnerMetricReport
+}
+
+// skip marks runner group skipped and finished so that the runner groups
+// starting after it won't wait forever.
+func (state *runnerGroupState) skip() {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	state.skipped = true
+	close(state.finishedCh)
+}
+
+// isSkipped returns true if runner group wasn't deployed.
+func (state *runnerGroupState) isSkipped() bool {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	return state.skipped
+}
+
+// abort marks runner group aborted. It returns false if it's been aborted.
+func (state *runnerGroupState) abort() bool {
+	aborted := false
+	state.abortOnce.Do(func() {
+		close(state.abortCh)
+		aborted = true
+	})
+	return aborted
+}
+
+// addUploadedRunner records runner which has uploaded report. The runner's
+// interim snapshot is dropped since report supersedes it.
+func (state *runnerGroupState) addUploadedRunner(runnerName string) {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	state.uploaded[runnerName] = struct{}{}
+	delete(state.interims, runnerName)
+}
+
+// hasUploadedRunner returns true if runner has uploaded report.
+func (state *runnerGroupState) hasUploadedRunner(runnerName string) bool {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	_, ok := state.uploaded[runnerName]
+	return ok
+}
+
+// setInterimReport replaces runner's interim snapshot.
+func (state *runnerGroupState) setInterimReport(runnerName string, report *types.RunnerMetricReport) {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	state.interims[runnerName] = report
+}
+
+// interimReport returns runner's latest interim snapshot, or nil.
+func (state *runnerGroupState) interimReport(runnerName string) *types.RunnerMetricReport {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	return state.interims[runnerName]
+}
+
+// uploadedRunners returns the runners which have uploaded report.
+func (state *runnerGroupState) uploadedRunners() []string {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	res := make([]string, 0, len(state.uploaded))
+	for name := range state.uploaded {
+		res = append(res, name)
+	}
+	return res
+}
+
+// newRunnerGroupStates validates runner groups' specs and returns state for
+// each runner group.
+func newRunnerGroupStates(groups []*group.Handler) ([]*runnerGroupState, error) {
+	specs := make([]*types.RunnerGroupSpec, 0, len(groups))
+	for _, g := range groups {
+		specs = append(specs, g.Spec())
+	}
+	return newRunnerGroupStatesFromSpecs(specs)
+}
+
+// newRunnerGroupStatesFromSpecs validates specs and returns state for each
+// spec.
+func newRunnerGroupStatesFromSpecs(specs []*types.RunnerGroupSpec) ([]*runnerGroupState, error) {
+	if err := types.ValidateRunnerGroupSpecs(specs); err != nil {
+		return nil, err
+	}
+
+	indexes := make(map[string]int, len(specs))
+	for idx, spec := range specs {
+		if spec.Name != "" {
+			indexes[spec.Name] = idx
+		}
+	}
+
+	states := make([]*runnerGroupState, 0, len(specs))
+	for _, spec := range specs {
+		state := &runnerGroupState{
+			deployedCh: make(chan struct{}),
+			finishedCh: make(chan struct{}),
+			abortCh:    make(chan struct{}),
+			dependency: -1,
+			uploaded:   map[string]struct{}{},
+			interims:   map[string]*types.RunnerMetricReport{},
+		}
+		if spec.StartAfter != nil {
+			state.dependency = indexes[spec.StartAfter.Name]
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+// deployRunnerGroups deploys runner groups. The runner groups without
+// startAfter and startDelay are deployed at once. The others are deployed in
+// background when their conditions are met.
 //
 // FIXME(weifu): should decouple URL from runner group.
 func (s *Server) deployRunnerGroups() error {
@@ -18,17 +170,31 @@ func (s *Server) deployRunnerGroups() error {
 		return err
 	}
 
-	uploadURL := fmt.Sprintf("http://%s/v1/runnergroups/$(POD_NAME)/result", targetAddr)
+	baseURL := fmt.Sprintf("http://%s", targetAddr)
 
 	var wg sync.WaitGroup
 	errCh := make(chan error, len(s.groups))
 	for idx := range s.groups {
+		g, state := s.groups[idx], s.states[idx]
+
+		spec := g.Spec()
+		if spec.StartAfter != nil || spec.StartDelay > 0 {
+			go s.deployRunnerGroupLater(idx, baseURL)
+			continue
+		}
+
 		wg.Add(1)
-		g := s.groups[idx]
 		go func() {
 			defer wg.Done()
 
-			errCh <- g.Deploy(context.Background(), uploadURL)
+			err := deployRunnerGroup(g, baseURL)
+			if err != nil {
+				// NOTE: Release the runner groups starting after it.
+				state.skip()
+				errCh <- fmt.Errorf("%s: %w", g.Name(), err)
+				return
+			}
+			close(state.deployedCh)
 		}()
 	}
 	wg.Wait()
@@ -42,6 +208,89 @@ func (s *Server) deployRunnerGroups() error {
 	return nil
 }
 
+// deployRunnerGroupLater deploys runner group after StartAfter's condition
+// is met and StartDelay. If the dependency was skipped or aborted, the runner
+// group is skipped as well.
+func (s *Server) deployRunnerGroupLater(idx int, baseURL string) {
+	g, state := s.groups[idx], s.states[idx]
+
+	skip := func(err error) {
+		klog.ErrorS(err, "skip runner group", "runner-group", g.Name())
+		state.skip()
+	}
+
+	if err := waitToStartRunnerGroup(s.states, idx, g.Name(), g.Spec()); err != nil {
+		skip(err)
+		return
+	}
+
+	if err := deployRunnerGroup(g, baseURL); err != nil {
+		skip(fmt.Errorf("failed to deploy: %w", err))
+		return
+	}
+	close(state.deployedCh)
+
+	// NOTE: The runner group might be aborted during deploying.
+	if isClosed(state.abortCh) {
+		if err := g.Abort(context.Background()); err != nil {
+			klog.ErrorS(err, "failed to abort runner group", "runner-group", g.Name())
+		}
+	}
+}
+
+// waitToStartRunnerGroup blocks until the runner group at idx meets spec's
+// StartAfter and StartDelay. It returns error if the runner group should be
+// skipped because it or its dependency was aborted or skipped.
+func waitToStartRunnerGroup(states []*runnerGroupState, idx int, name string, spec *types.RunnerGroupSpec) error {
+	state := states[idx]
+
+	if state.dependency >= 0 {
+		dep := states[state.dependency]
+
+		waitCh := dep.finishedCh
+		if spec.StartAfter.State == types.RunnerGroupStatusStateRunning {
+			waitCh = dep.deployedCh
+		}
+
+		klog.V(2).InfoS("Waiting for runner group", "runner-group", name,
+			"start-after", spec.StartAfter.Name, "state", spec.StartAfter.State)
+		select {
+		case <-waitCh:
+		case <-dep.finishedCh:
+		case <-dep.abortCh:
+		case <-state.abortCh:
+			return fmt.Errorf("runner group was aborted before deploying")
+		}
+
+		if isClosed(dep.abortCh) {
+			return fmt.Errorf("runner group %s was aborted", spec.StartAfter.Name)
+		}
+		if dep.isSkipped() {
+			return fmt.Errorf("runner group %s wasn't deployed", spec.StartAfter.Name)
+		}
+	}
+
+	if spec.StartDelay > 0 {
+		klog.V(2).InfoS("Delaying runner group", "runner-group", name, "delay", spec.StartDelay)
+		select {
+		case <-time.After(spec.StartDelay):
+		case <-state.abortCh:
+			return fmt.Errorf("runner group was aborted before deploying")
+		}
+	}
+	return nil
+}
+
+// deployRunnerGroup deploys runner group with server's endpoints.
+//
+// NOTE: Please update Server.Run if endpoint has been changed.
+func deployRunnerGroup(g *group.Handler, baseURL string) error {
+	uploadURL := fmt.Sprintf("%s/v1/runnergroups/$(POD_NAME)/result", baseURL)
+	interimURL := fmt.Sprintf("%s/v1/runnergroups/$(POD_NAME)/interim", baseURL)
+	startBarrierURL := fmt.Sprintf("%s/v1/runnergroups/%s/start?runner=$(POD_NAME)", baseURL, g.Name())
+	return g.Deploy(context.Background(), uploadURL, interimURL, startBarrierURL)
+}
+
 // waitForRunnerGroups watches all runner groups and marks summary ready until
 // all runner groups finish.
 func (s *Server) waitForRunnerGroups() {
@@ -49,22 +298,43 @@ func (s *Server) waitForRunnerGroups() {
 
 	for idx := range s.groups {
 		wg.Add(1)
-		g := s.groups[idx]
+		g, state := s.groups[idx], s.states[idx]
 		go func() {
 			defer wg.Done()
 
+			select {
+			case <-state.deployedCh:
+			case <-state.finishedCh:
+				// skipped
+				return
+			}
+
 			// FIXME(weifu): remove panic here
 			if err := g.Wait(context.TODO()); err != nil {
 				panic(err)
 			}
+			close(state.finishedCh)
 		}()
 	}
 	wg.Wait()
 
-	s.report = buildRunnerGroupSummary(s.store, s.groups)
+	s.report = buildRunnerGroupSummary(s.store, s.groups, s.states, s.flowControl, false)
+	if s.scraper != nil {
+		s.report.APIServerMetrics = request.DiffAPIServerMetrics(s.apiserverMetricsBefore, s.scraper.Scrape(context.TODO()))
+	}
 	close(s.readyCh)
 }
 
+// isClosed returns true if the channel has been closed.
+func isClosed(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
 // firstNoLocalAddr returns first non-local address.
 func (s *Server) firstNonLocalAddr() (string, error) {
 	for _, lis := range s.listeners {
@@ -0,0 +1,117 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Azure/kperf/api/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitToStartRunnerGroup(t *testing.T) {
+	specs := []*types.RunnerGroupSpec{
+		{Name: "writers"},
+		{Name: "readers", StartAfter: &types.RunnerGroupStartAfter{Name: "writers"}},
+		{Name: "watchers", StartAfter: &types.RunnerGroupStartAfter{Name: "readers", State: types.RunnerGroupStatusStateRunning}},
+		{Name: "delayed", StartDelay: 100 * time.Millisecond},
+	}
+
+	start := func(states []*runnerGroupState, idx int) <-chan error {
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- waitToStartRunnerGroup(states, idx, specs[idx].Name, specs[idx])
+		}()
+		return errCh
+	}
+
+	assertBlocked := func(t *testing.T, errCh <-chan error) {
+		select {
+		case err := <-errCh:
+			t.Fatalf("expected to wait, but got %v", err)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	t.Run("start after finished and running", func(t *testing.T) {
+		states, err := newRunnerGroupStatesFromSpecs(specs)
+		require.NoError(t, err)
+
+		readersCh := start(states, 1)
+		watchersCh := start(states, 2)
+
+		close(states[0].deployedCh)
+		assertBlocked(t, readersCh)
+
+		close(states[0].finishedCh)
+		require.NoError(t, <-readersCh)
+		assertBlocked(t, watchersCh)
+
+		close(states[1].deployedCh)
+		require.NoError(t, <-watchersCh)
+	})
+
+	t.Run("skipped dependency skips dependents", func(t *testing.T) {
+		states, err := newRunnerGroupStatesFromSpecs(specs)
+		require.NoError(t, err)
+
+		readersCh := start(states, 1)
+		watchersCh := start(states, 2)
+
+		states[0].skip()
+		require.Error(t, <-readersCh)
+		assertBlocked(t, watchersCh)
+
+		// NOTE: deployRunnerGroupLater marks the skipped runner group.
+		states[1].skip()
+		require.Error(t, <-watchersCh)
+	})
+
+	t.Run("aborted dependency skips dependents", func(t *testing.T) {
+		states, err := newRunnerGroupStatesFromSpecs(specs)
+		require.NoError(t, err)
+
+		readersCh := start(states, 1)
+		assert.True(t, states[0].abort())
+		assert.False(t, states[0].abort())
+		require.Error(t, <-readersCh)
+	})
+
+	t.Run("start delay", func(t *testing.T) {
+		states, err := newRunnerGroupStatesFromSpecs(specs)
+		require.NoError(t, err)
+
+		begin := time.Now()
+		require.NoError(t, <-start(states, 3))
+		assert.GreaterOrEqual(t, time.Since(begin), specs[3].StartDelay)
+	})
+
+	t.Run("abort during start delay", func(t *testing.T) {
+		states, err := newRunnerGroupStatesFromSpecs(specs)
+		require.NoError(t, err)
+
+		delayedCh := start(states, 3)
+		states[3].abort()
+		require.Error(t, <-delayedCh)
+	})
+}
+
+func TestNewRunnerGroupStatesFromSpecs(t *testing.T) {
+	states, err := newRunnerGroupStatesFromSpecs([]*types.RunnerGroupSpec{
+		{Name: "a", StartAfter: &types.RunnerGroupStartAfter{Name: "b"}},
+		{Name: "b"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, states[0].dependency)
+	assert.Equal(t, -1, states[1].dependency)
+
+	_, err = newRunnerGroupStatesFromSpecs([]*types.RunnerGroupSpec{
+		{Name: "a", StartAfter: &types.RunnerGroupStartAfter{Name: "b"}},
+		{Name: "b", StartAfter: &types.RunnerGroupStartAfter{Name: "a"}},
+	})
+	assert.Error(t, err)
+}
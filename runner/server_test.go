@@ -0,0 +1,104 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Azure/kperf/api/types"
+	"github.com/Azure/kperf/runner/group"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// newTestRunnerPod returns running pod created by runner group's job.
+func newTestRunnerPod(namespace, jobName, name string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels: map[string]string{
+				"batch.kubernetes.io/job-name": jobName,
+				"job-name":                     jobName,
+			},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+}
+
+// postTestRunnerReport posts runner's report to server's endpoint and
+// returns the status code.
+func postTestRunnerReport(t *testing.T, url string, report *types.RunnerMetricReport) int {
+	data, err := json.Marshal(report)
+	require.NoError(t, err)
+
+	resp, err := http.Post(url, "application/json", bytes.NewReader(data))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestServerPartialSummaryWithInterimReport(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		newTestRunnerPod("kperf", "readers", "readers-0"),
+		newTestRunnerPod("kperf", "readers", "readers-1"),
+	)
+	h, err := group.NewHandler(clientset, "kperf", "readers", &types.RunnerGroupSpec{Name: "readers", Count: 2}, "kperf")
+	require.NoError(t, err)
+
+	s, err := NewServer(t.TempDir(), nil, []*group.Handler{h})
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(s.router())
+	defer srv.Close()
+
+	// NOTE: readers-0 finished while readers-1 is still running and only
+	// pushed interim snapshots.
+	assert.Equal(t, http.StatusCreated,
+		postTestRunnerReport(t, srv.URL+"/v1/runnergroups/readers-0/result", newTestRunnerReport(100, 0.1)))
+	assert.Equal(t, http.StatusCreated,
+		postTestRunnerReport(t, srv.URL+"/v1/runnergroups/readers-1/interim", newTestRunnerReport(10, 0.2)))
+	assert.Equal(t, http.StatusCreated,
+		postTestRunnerReport(t, srv.URL+"/v1/runnergroups/readers-1/interim", newTestRunnerReport(40, 0.2)))
+
+	// The interim snapshot is stale once report has been uploaded.
+	assert.Equal(t, http.StatusConflict,
+		postTestRunnerReport(t, srv.URL+"/v1/runnergroups/readers-0/interim", newTestRunnerReport(10, 0.2)))
+	assert.Equal(t, http.StatusNotFound,
+		postTestRunnerReport(t, srv.URL+"/v1/runnergroups/writers-0/interim", newTestRunnerReport(10, 0.2)))
+
+	resp, err := http.Get(srv.URL + "/v1/runnergroups/summary?partial")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	summary := &types.RunnerGroupsReport{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(summary))
+
+	assert.True(t, summary.Partial)
+	assert.Equal(t, []string{"readers-0", "readers-1"}, summary.IncludedRunners)
+	assert.Equal(t, 140, summary.Total)
+
+	require.Len(t, summary.Groups, 1)
+	runners := summary.Groups[0].Runners
+	require.Len(t, runners, 2)
+	assert.True(t, runners[0].HasReport)
+	assert.False(t, runners[0].Interim)
+	assert.False(t, runners[1].HasReport)
+	assert.True(t, runners[1].Interim)
+	assert.Equal(t, types.RunnerReportStateRunning, runners[1].State)
+
+	// The final summary only merges uploaded reports.
+	final := buildRunnerGroupSummary(s.store, s.groups, s.states, s.flowControl, false)
+	assert.Equal(t, 100, final.Total)
+	assert.False(t, final.Groups[0].Runners[1].Interim)
+}
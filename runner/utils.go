@@ -4,13 +4,13 @@
 package runner
 
 import (
-	"container/list"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"sort"
 	"strings"
 	"time"
 
@@ -19,6 +19,7 @@ import (
 	"github.com/Azure/kperf/runner/group"
 	"github.com/Azure/kperf/runner/localstore"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/klog/v2"
 )
 
@@ -59,99 +60,316 @@ func buildNetListeners(addrs []string) (_ []net.Listener, retErr error) {
 }
 
 // buildRunnerGroupSummary returns aggrecated summary from runner groups' report.
-func buildRunnerGroupSummary(s *localstore.Store, groups []*group.Handler) *types.RunnerMetricReport {
-	totalBytes := int64(0)
-	totalResp := 0
-	latenciesByURL := map[string]*list.List{}
-	errs := []types.ResponseError{}
-	errStats := map[string]int32{}
-	maxDuration := 0 * time.Second
+// With interim, the runners which haven't uploaded report are merged by their
+// latest interim snapshots.
+func buildRunnerGroupSummary(s *localstore.Store, groups []*group.Handler, states []*runnerGroupState, flowControl *types.RunnerGroupFlowControl, interim bool) *types.RunnerGroupsReport {
+	allReports := map[string]*types.RunnerMetricReport{}
+	groupReports := make([]types.RunnerGroupReport, 0, len(groups))
 
 	for idx := range groups {
 		g := groups[idx]
 
-		pods, err := g.Pods(context.TODO())
-		if err != nil {
-			klog.V(2).ErrorS(err, "failed to list runners", "runner-group", g.Name())
-			continue
+		info := g.Info(context.TODO())
+		// NOTE: The runner group might be aborted before deploying.
+		if isClosed(states[idx].abortCh) {
+			info.Status.State = types.RunnerGroupStatusStateAborted
+		}
+		groupReport := types.RunnerGroupReport{
+			Name:   g.Name(),
+			Spec:   info.Spec,
+			Status: info.Status,
 		}
 
-		for _, pod := range pods {
-			data, err := readBlob(s, pod.Name)
-			if err != nil {
-				klog.V(2).ErrorS(err, "failed to read report", "runner", pod.Name)
-				continue
-			}
+		runners, reports := collectRunnerReports(s, g, states[idx], interim)
+		for name, report := range reports {
+			allReports[name] = report
+		}
+		groupReport.Runners = runners
 
-			report := types.RunnerMetricReport{}
+		groupReport.Report = mergeRunnerMetricReports(reports)
+		groupReports = append(groupReports, groupReport)
+	}
 
-			err = json.Unmarshal(data, &report)
-			if err != nil {
-				klog.V(2).ErrorS(err, "failed to unmarshal", "runner", pod.Name)
+	return &types.RunnerGroupsReport{
+		RunnerMetricReport: *mergeRunnerMetricReports(allReports),
+		Groups:             groupReports,
+		FlowControl:        flowControl,
+	}
+}
+
+// collectRunnerReports returns runners' status sorted by name and uploaded
+// reports keyed by runner's name. With interim, it returns the latest interim
+// snapshot for the runner which hasn't uploaded report.
+func collectRunnerReports(s *localstore.Store, g *group.Handler, state *runnerGroupState, interim bool) ([]types.RunnerReportStatus, map[string]*types.RunnerMetricReport) {
+	runners := listRunnerStatuses(s, g, state)
+
+	reports := map[string]*types.RunnerMetricReport{}
+	for idx := range runners {
+		status := &runners[idx]
+		if !status.HasReport {
+			if !interim {
 				continue
 			}
-
-			// update totalReceivedBytes
-			totalBytes += report.TotalReceivedBytes
-
-			// update latencies
-			for u, l := range report.LatenciesByURL {
-				latencies, ok := latenciesByURL[u]
-				if !ok {
-					latenciesByURL[u] = list.New()
-					latencies = latenciesByURL[u]
-				}
-				for _, v := range l {
-					totalResp++
-					latencies.PushBack(v)
-				}
+			if report := state.interimReport(status.Name); report != nil {
+				status.Interim = true
+				status.SelfStats = report.SelfStats
+				reports[status.Name] = report
 			}
+			continue
+		}
+
+		report, err := readRunnerReport(s, status.Name)
+		if err != nil {
+			klog.V(2).ErrorS(err, "failed to read report", "runner", status.Name)
+			continue
+		}
+		status.Interrupted = report.Interrupted
+		status.SelfStats = report.SelfStats
+		reports[status.Name] = report
+	}
+	return runners, reports
+}
 
-			// update error stats
-			mergeErrorStat(errStats, report.ErrorStats)
-			errs = append(errs, report.Errors...)
-			report.Errors = nil
+// listRunnerStatuses returns runners' status sorted by name. It only checks
+// whether report exists and doesn't read it.
+func listRunnerStatuses(s *localstore.Store, g *group.Handler, state *runnerGroupState) []types.RunnerReportStatus {
+	pods, err := g.Pods(context.TODO())
+	if err != nil {
+		klog.V(2).ErrorS(err, "failed to list runners", "runner-group", g.Name())
+	}
 
-			// update max duration
-			rDur, err := time.ParseDuration(report.Duration)
-			if err != nil {
-				klog.V(2).ErrorS(err, "failed to parse duration", "runner",
-					pod.Name, "duration", report.Duration)
-			}
-			if rDur > maxDuration {
-				maxDuration = rDur
+	// NOTE: The pods are deleted if runner group has been aborted.
+	// The uploaded runners are still counted.
+	phases := map[string]corev1.PodPhase{}
+	for _, pod := range pods {
+		phases[pod.Name] = pod.Status.Phase
+	}
+	for _, name := range state.uploadedRunners() {
+		if _, ok := phases[name]; !ok {
+			phases[name] = corev1.PodUnknown
+		}
+	}
+
+	runners := make([]types.RunnerReportStatus, 0, len(phases))
+	for name, phase := range phases {
+		hasReport, err := s.Exists(name)
+		if err != nil {
+			klog.V(2).ErrorS(err, "failed to check report", "runner", name)
+		}
+
+		runners = append(runners, types.RunnerReportStatus{
+			Name:      name,
+			State:     runnerReportState(phase, hasReport),
+			HasReport: hasReport,
+		})
+	}
+	sort.Slice(runners, func(i, j int) bool {
+		return runners[i].Name < runners[j].Name
+	})
+	return runners
+}
+
+// readRunnerReport reads runner's report from localstore.
+func readRunnerReport(s *localstore.Store, runnerName string) (*types.RunnerMetricReport, error) {
+	data, err := readBlob(s, runnerName)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &types.RunnerMetricReport{}
+	if err := json.Unmarshal(data, report); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal: %w", err)
+	}
+	return report, nil
+}
+
+// runnerReportState returns runner's state based on pod's phase and whether
+// report has been uploaded.
+//
+// NOTE: The interrupted runner exits with zero code after uploading partial
+// report, so that failed pod with report means runner uploaded the report
+// recording its failure.
+func runnerReportState(phase corev1.PodPhase, hasReport bool) types.RunnerReportState {
+	switch {
+	case phase == corev1.PodFailed:
+		return types.RunnerReportStateFailed
+	case hasReport:
+		return types.RunnerReportStateSucceeded
+	case phase == corev1.PodPending:
+		return types.RunnerReportStatePending
+	case phase == corev1.PodRunning:
+		return types.RunnerReportStateRunning
+	default:
+		return types.RunnerReportStateMissingReport
+	}
+}
+
+// mergeRunnerMetricReports merges runners' reports keyed by runner's name.
+//
+// NOTE: The runners' APIServerMetrics are omitted because runners observe the
+// same kube-apiserver replicas. The server scrapes them once for summary.
+func mergeRunnerMetricReports(reports map[string]*types.RunnerMetricReport) *types.RunnerMetricReport {
+	totalBytes := int64(0)
+	totalResp := 0
+	latenciesByURL := map[string]*metrics.Histogram{}
+	statsByTag := map[string]*types.RequestStats{}
+	statsByResource := map[string]*types.RequestStats{}
+	errs := []metrics.WeightedErrors{}
+	errCounts := [][]types.ErrorCount{}
+	errStats := map[string]int32{}
+	priorityLevels := map[string]*types.PriorityLevelStats{}
+	timeSeries := []types.TimeSeriesBucket{}
+	timeSeriesInterval := ""
+	selfStats := []*types.RunnerSelfStats{}
+	warnings := []string{}
+	interrupted := false
+	maxDuration := 0 * time.Second
+
+	names := make([]string, 0, len(reports))
+	for name := range reports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		report := reports[name]
+
+		// update total requests and totalReceivedBytes
+		totalResp += countRequests(report)
+		totalBytes += report.TotalReceivedBytes
+
+		// update latencies
+		for u, h := range report.HistogramsByURL {
+			latencies, ok := latenciesByURL[u]
+			if !ok {
+				latencies = metrics.NewHistogram()
+				latenciesByURL[u] = latencies
 			}
+			latencies.Merge(h)
+		}
+
+		// update request stats
+		metrics.MergeRequestStats(statsByTag, report.StatsByTag)
+		metrics.MergeRequestStats(statsByResource, report.StatsByResource)
+
+		// update priority levels
+		metrics.MergePriorityLevelStats(priorityLevels, report.PriorityLevels)
+
+		// update time series
+		timeSeries = metrics.MergeTimeSeries(timeSeries, report.TimeSeries)
+		if timeSeriesInterval == "" {
+			timeSeriesInterval = report.TimeSeriesInterval
+		}
+
+		// update error stats
+		mergeErrorStat(errStats, report.ErrorStats)
+		errCounts = append(errCounts, report.ErrorCounts)
+		errs = append(errs, metrics.WeightedErrors{
+			Samples: report.Errors,
+			Seen:    countErrors(report.ErrorCounts),
+		})
+
+		// update runner's own resource usage
+		selfStats = append(selfStats, report.SelfStats)
+
+		// update warnings
+		for _, w := range report.Warnings {
+			warnings = append(warnings, fmt.Sprintf("%s: %s", name, w))
+		}
+
+		if report.Error != "" {
+			warnings = append(warnings, fmt.Sprintf("%s: runner failed: %s", name, report.Error))
+		}
+
+		if report.Interrupted {
+			interrupted = true
+			warnings = append(warnings, fmt.Sprintf("%s: runner was interrupted", name))
+		}
+
+		// update max duration
+		rDur, err := time.ParseDuration(report.Duration)
+		if err != nil {
+			klog.V(2).ErrorS(err, "failed to parse duration", "runner",
+				name, "duration", report.Duration)
+		}
+		if rDur > maxDuration {
+			maxDuration = rDur
+		}
+	}
+
+	var percentileLatenciesByURL map[string][][2]float64
+	if len(latenciesByURL) > 0 {
+		percentileLatenciesByURL = make(map[string][][2]float64, len(latenciesByURL))
+		for u, h := range latenciesByURL {
+			percentileLatenciesByURL[u] = h.PercentileLatencies()
 		}
 	}
 
-	percentileLatenciesByURL := map[string][][2]float64{}
+	// NOTE: Each request belongs to exactly one resource group.
+	latencies := metrics.NewHistogram()
+	for _, rs := range statsByResource {
+		latencies.Merge(rs.Histogram)
+	}
+
+	for _, group := range []map[string]*types.RequestStats{statsByTag, statsByResource} {
+		for _, rs := range group {
+			rs.PercentileLatencies = metrics.MergeHistograms(rs.Histogram).PercentileLatencies()
+			rs.Histogram = nil
+		}
+	}
 
-	latencies := make([]float64, 0, totalResp)
-	for u, l := range latenciesByURL {
-		lInSlice := listToSliceFloat64(l)
+	for _, pl := range priorityLevels {
+		pl.PercentileLatencies = metrics.MergeHistograms(pl.Histogram).PercentileLatencies()
+		pl.Histogram = nil
+	}
 
-		latencies = append(latencies, lInSlice...)
-		percentileLatenciesByURL[u] = metrics.BuildPercentileLatencies(lInSlice)
+	for idx := range timeSeries {
+		b := &timeSeries[idx]
+		b.PercentileLatencies = metrics.MergeHistograms(b.Histogram).PercentileLatencies()
+		b.Histogram = nil
 	}
 
 	return &types.RunnerMetricReport{
 		Total:                    totalResp,
-		Errors:                   errs,
+		Errors:                   metrics.MergeErrorSamples(metrics.DefaultErrorSamples, errs...),
 		ErrorStats:               errStats,
+		ErrorCounts:              metrics.MergeErrorCounts(errCounts...),
 		Duration:                 maxDuration.String(),
 		TotalReceivedBytes:       totalBytes,
-		PercentileLatencies:      metrics.BuildPercentileLatencies(latencies),
+		PercentileLatencies:      latencies.PercentileLatencies(),
 		PercentileLatenciesByURL: percentileLatenciesByURL,
+		StatsByTag:               statsByTag,
+		StatsByResource:          statsByResource,
+		PriorityLevels:           priorityLevels,
+		TimeSeriesInterval:       timeSeriesInterval,
+		TimeSeries:               timeSeries,
+		SelfStats:                metrics.MergeRunnerSelfStats(selfStats...),
+		Warnings:                 warnings,
+		Interrupted:              interrupted,
+	}
+}
+
+// countRequests returns the number of finished requests, including failed
+// requests, from report's ResponseStats.
+//
+// NOTE: Each request belongs to exactly one resource group. It doesn't use
+// report's Total, which is the number of requests in load profile unless
+// runner was interrupted.
+func countRequests(report *types.RunnerMetricReport) int {
+	total := 0
+	for _, rs := range report.StatsByResource {
+		total += rs.Total
 	}
+	return total
 }
 
-// listToSliceFloat64 converts list.List into []float64.
-func listToSliceFloat64(l *list.List) []float64 {
-	res := make([]float64, 0, l.Len())
-	for e := l.Front(); e != nil; e = e.Next() {
-		res = append(res, e.Value.(float64))
+// countErrors returns the total number of errors.
+func countErrors(counts []types.ErrorCount) int64 {
+	total := int64(0)
+	for _, c := range counts {
+		total += c.Count
 	}
-	return res
+	return total
 }
 
 // mergeErrorStat merges two error stats.
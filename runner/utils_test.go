@@ -0,0 +1,157 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package runner
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Azure/kperf/api/types"
+	"github.com/Azure/kperf/metrics"
+	"github.com/Azure/kperf/runner/localstore"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// newTestRunnerReport returns report like the one uploaded by runner in
+// runner group, which doesn't have latencies by URL.
+func newTestRunnerReport(total int, seconds float64) *types.RunnerMetricReport {
+	h := metrics.NewHistogram()
+	for i := 0; i < total; i++ {
+		h.Observe(seconds)
+	}
+
+	stats := func() map[string]*types.RequestStats {
+		return map[string]*types.RequestStats{
+			"list-pods": {Total: total, Histogram: h.Snapshot()},
+		}
+	}
+	return &types.RunnerMetricReport{
+		Total:           total,
+		Duration:        "10s",
+		StatsByTag:      stats(),
+		StatsByResource: stats(),
+	}
+}
+
+// commitRunnerReport stores runner's report as it's uploaded.
+func commitRunnerReport(t *testing.T, s *localstore.Store, runnerName string, report *types.RunnerMetricReport) {
+	data, err := json.Marshal(report)
+	require.NoError(t, err)
+
+	w, err := s.OpenWriter()
+	require.NoError(t, err)
+	_, err = w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Commit(runnerName))
+}
+
+func TestRunnerGroupSummaryURLSLO(t *testing.T) {
+	s, err := localstore.NewStore(t.TempDir())
+	require.NoError(t, err)
+
+	commitRunnerReport(t, s, "runner-0", newTestRunnerReport(100, 0.1))
+	commitRunnerReport(t, s, "runner-1", newTestRunnerReport(100, 0.2))
+
+	reports := map[string]*types.RunnerMetricReport{}
+	for _, name := range []string{"runner-0", "runner-1"} {
+		report, err := readRunnerReport(s, name)
+		require.NoError(t, err)
+		reports[name] = report
+	}
+	summary := mergeRunnerMetricReports(reports)
+	assert.Equal(t, 200, summary.Total)
+
+	urlSLO := &types.SLO{
+		P99Latencies: []types.LatencyThreshold{
+			{URL: "/api/v1/pods", Max: time.Second},
+		},
+	}
+	require.NoError(t, urlSLO.Validate())
+	assert.Error(t, ValidateRunnerGroupSLO(urlSLO))
+
+	tagSLO := &types.SLO{
+		P99Latencies: []types.LatencyThreshold{
+			{Tag: "list-pods", Max: time.Second},
+		},
+	}
+	require.NoError(t, ValidateRunnerGroupSLO(tagSLO))
+
+	verdict := metrics.EvaluateSLO(tagSLO, summary)
+	require.Len(t, verdict.Checks, 1)
+	assert.True(t, verdict.Passed, verdict.Checks[0].Message)
+	assert.InDelta(t, 0.2, verdict.Checks[0].Actual, 0.01)
+}
+
+func TestMergeRunnerMetricReportsTotal(t *testing.T) {
+	// NOTE: 10 of 110 requests failed.
+	finished := newTestRunnerReport(100, 0.1)
+	finished.Total = 110
+	finished.StatsByResource["list-pods"].Total = 110
+	finished.StatsByResource["list-pods"].ErrorStats = map[string]int32{"http/500": 10}
+
+	// NOTE: The interrupted runner only finished 40 of 100 requests.
+	interrupted := newTestRunnerReport(40, 0.1)
+	interrupted.Interrupted = true
+
+	summary := mergeRunnerMetricReports(map[string]*types.RunnerMetricReport{
+		"runner-0": finished,
+		"runner-1": interrupted,
+	})
+	assert.Equal(t, 150, summary.Total)
+	assert.True(t, summary.Interrupted)
+}
+
+func TestMergeRunnerMetricReportsSelfStats(t *testing.T) {
+	busy := newTestRunnerReport(10, 0.1)
+	busy.SelfStats = &types.RunnerSelfStats{CPUSeconds: 10, MaxCPUCores: 1.9, AvailableCPUCores: 2}
+
+	idle := newTestRunnerReport(10, 0.1)
+	idle.SelfStats = &types.RunnerSelfStats{CPUSeconds: 1, MaxCPUCores: 0.2, AvailableCPUCores: 2}
+	idle.APIServerMetrics = map[string]*types.APIServerMetricsDelta{
+		"10.0.0.1:443": {Duration: "10s"},
+	}
+
+	summary := mergeRunnerMetricReports(map[string]*types.RunnerMetricReport{
+		"runner-0": busy,
+		"runner-1": idle,
+		// NOTE: The report without selfStats is skipped.
+		"runner-2": newTestRunnerReport(10, 0.1),
+	})
+	require.NotNil(t, summary.SelfStats)
+	assert.Equal(t, float64(11), summary.SelfStats.CPUSeconds)
+	assert.Equal(t, 1.9, summary.SelfStats.MaxCPUCores)
+	assert.Equal(t, float64(2), summary.SelfStats.AvailableCPUCores)
+	assert.Nil(t, summary.APIServerMetrics)
+
+	assert.Nil(t, mergeRunnerMetricReports(nil).SelfStats)
+}
+
+func TestRunnerReportState(t *testing.T) {
+	for _, tc := range []struct {
+		phase     corev1.PodPhase
+		hasReport bool
+		expected  types.RunnerReportState
+	}{
+		// NOTE: The interrupted runner exits with zero code after uploading
+		// partial report. The failed runner with report has uploaded the
+		// report recording its failure.
+		{corev1.PodFailed, true, types.RunnerReportStateFailed},
+		{corev1.PodFailed, false, types.RunnerReportStateFailed},
+		{corev1.PodSucceeded, true, types.RunnerReportStateSucceeded},
+		{corev1.PodSucceeded, false, types.RunnerReportStateMissingReport},
+		{corev1.PodRunning, true, types.RunnerReportStateSucceeded},
+		{corev1.PodRunning, false, types.RunnerReportStateRunning},
+		{corev1.PodPending, false, types.RunnerReportStatePending},
+		// The pods are deleted if runner group was aborted.
+		{corev1.PodUnknown, true, types.RunnerReportStateSucceeded},
+		{corev1.PodUnknown, false, types.RunnerReportStateMissingReport},
+	} {
+		assert.Equal(t, tc.expected, runnerReportState(tc.phase, tc.hasReport),
+			"phase: %s, hasReport: %v", tc.phase, tc.hasReport)
+	}
+}